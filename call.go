@@ -78,7 +78,7 @@ func NewCall(functionName string, param string, path []interface{}) (*Call, erro
 	}
 
 	if !validCallFunc(functionName) {
-		return nil, fmt.Errorf("invalid call function '%s'", functionName)
+		return nil, &InvalidCallError{Function: functionName, Path: path}
 	}
 
 	return &Call{
@@ -99,7 +99,7 @@ func NewRawCall(callString string) (*Call, bool, error) {
 		param := call[3]
 
 		if !validCallFunc(function) {
-			return nil, false, fmt.Errorf("invalid call function '%s'", function)
+			return nil, false, &InvalidCallError{Function: function}
 		}
 
 		return &Call{
@@ -182,12 +182,9 @@ func (c Call) FullName() string {
 	return "%" + fmt.Sprintf("{%s:%s}", c.FunctionName, c.Param)
 }
 
+// Path returns the path where the call is used, see [FormatPath].
 func (c Call) Path() string {
-	var segments []string
-	for _, seg := range c.Identifier {
-		segments = append(segments, fmt.Sprint(seg))
-	}
-	return strings.Join(segments, ".")
+	return FormatPath(c.Identifier)
 }
 
 func validCallFunc(funcName string) bool {
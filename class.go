@@ -22,6 +22,10 @@ type Class struct {
 	Name string
 	// Configuration holds Skipper-relevant configuration inside the class
 	Configuration *SkipperConfig
+	// Root is the name of the class root from which the class was loaded, see [ClassRoot].
+	Root string
+	// Shadowed are the classes with the same name from earlier class roots, which are hidden by this class.
+	Shadowed []*Class
 }
 
 // NewClass will create a new class, given a raw YamlFile and the relative filePath from inside the inventory.
@@ -39,18 +43,19 @@ func NewClass(file *YamlFile, relativeClassPath string) (*Class, error) {
 	// class file cannot be empty, there must be exactly one yaml root-key which must define a map
 	val := reflect.ValueOf(file.Data)
 	if val.Kind() != reflect.Map {
-		return nil, fmt.Errorf("class '%s' root key does not define a map", name)
+		return nil, newFileError(file, nil, fmt.Errorf("class '%s' root key does not define a map", name))
 	}
 	if len(val.MapKeys()) == 0 {
-		return nil, fmt.Errorf("class '%s' does not have a root-key", name)
+		return nil, newFileError(file, nil, fmt.Errorf("class '%s' does not have a root-key", name))
 	}
-	if len(val.MapKeys()) > 1 {
-		return nil, fmt.Errorf("class '%s' has more than one root-key which is currently not supported. Root Keys: %v", name, val.MapKeys())
+	rootKeys := file.Data.sortedKeys()
+	if len(rootKeys) > 1 {
+		return nil, newFileError(file, []interface{}{rootKeys[1]}, fmt.Errorf("class '%s' has more than one root-key which is currently not supported. Root Keys: %v", name, rootKeys))
 	}
 
-	fileName := strings.Split(path.Base(file.Path), ".yaml")[0]
-	if !strings.EqualFold(fileName, fmt.Sprint(val.MapKeys()[0].String())) {
-		return nil, fmt.Errorf("the root key in the '%s' class differs from the filename: %s", val.MapKeys()[0], fileName)
+	fileName := strings.TrimSuffix(path.Base(file.Path), path.Ext(file.Path))
+	if !strings.EqualFold(fileName, rootKeys[0]) {
+		return nil, newFileError(file, []interface{}{rootKeys[0]}, fmt.Errorf("the root key in the '%s' class differs from the filename: %s", rootKeys[0], fileName))
 	}
 
 	class := &Class{
@@ -61,7 +66,7 @@ func NewClass(file *YamlFile, relativeClassPath string) (*Class, error) {
 	// load skipper config
 	config, err := LoadSkipperConfig(file, class.RootKey())
 	if err != nil {
-		return nil, err
+		return nil, newFileError(file, []interface{}{class.RootKey(), skipperKey}, err)
 	}
 	class.Configuration = config
 
@@ -73,6 +78,25 @@ func (c *Class) Data() *Data {
 	return &c.File.Data
 }
 
+// NestedData returns the class data nested under the class path, which is how the class is merged into the inventory.
+// The class `foo.bar.baz` with the root key `baz` results in `Data{"foo": Data{"bar": Data{"baz": <class data>}}}`.
+// A class without any data results in an empty Data at the class path.
+func (c *Class) NestedData() Data {
+	rootKey := c.RootKey()
+	value := (*c.Data())[rootKey]
+	if value == nil {
+		value = make(Data)
+	}
+
+	segments := c.NameAsIdentifier()
+	nested := Data{rootKey: value}
+	for i := len(segments) - 2; i >= 0; i-- {
+		nested = Data{segments[i].(string): nested}
+	}
+
+	return nested
+}
+
 // RootKey returns the root key name of the class.
 func (c *Class) RootKey() string {
 	val := reflect.ValueOf(c.Data()).Elem()
@@ -82,6 +106,11 @@ func (c *Class) RootKey() string {
 	return val.MapKeys()[0].String()
 }
 
+// configPath returns the path of the given key of the Skipper configuration inside the class file.
+func (c *Class) configPath(key string) []interface{} {
+	return []interface{}{c.RootKey(), skipperKey, key}
+}
+
 // NameAsIdentifier returns the class name as an identifier used by skipper.
 // The name is a dot-separated list of values (e.g. 'foo.bar.baz').
 // The returned identifier is a []interface which the values and can be used to address the class in Data.
@@ -115,7 +144,7 @@ func classYamlFileLoader(classList *[]*Class) YamlFileLoaderFunc {
 	return func(file *YamlFile, relativePath string) error {
 		class, err := NewClass(file, relativePath)
 		if err != nil {
-			return fmt.Errorf("%s: %w", file.Path, err)
+			return err
 		}
 		(*classList) = append((*classList), class)
 
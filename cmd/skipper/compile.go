@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+// stringList is a flag.Value which can be set multiple times.
+// Every value may also be a comma-separated list.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		*l = append(*l, v)
+	}
+	return nil
+}
+
+func runCompile(args []string) error {
+	var (
+		projectPath   string
+		inventoryPath string
+		templatePath  string
+		outputPath    string
+		targets       stringList
+		revealSecrets bool
+		skipSecrets   bool
+		allowNoValue  bool
+		strictMerge   bool
+		strict        bool
+		allowed       stringList
+		concurrency   int
+	)
+
+	flags := flag.NewFlagSet("compile", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: skipper compile [flags] [target...]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Compiles the given targets. If no target is given, all targets are compiled.")
+		fmt.Fprintln(flags.Output(), "If a project file exists, the paths are loaded from it. Path flags take precedence over the project file.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&projectPath, "project", skipper.ProjectFileName, "path to the project file, it is only required to exist if the flag is set explicitly")
+	flags.StringVar(&inventoryPath, "inventory", "inventory", "path to the inventory folder which contains the 'classes', 'targets' and 'secrets' folders")
+	flags.StringVar(&templatePath, "templates", "templates", "path to the templates folder")
+	flags.StringVar(&outputPath, "output", "compiled", "path to the output folder, every target is compiled into '<output>/<target>'")
+	flags.Var(&targets, "target", "target to compile, can be repeated or comma-separated (default: all targets)")
+	flags.BoolVar(&revealSecrets, "reveal-secrets", false, "replace secrets with their actual values (CAUTION: only use this if the compiled output is ephemeral)")
+	flags.BoolVar(&skipSecrets, "skip-secrets", false, "skip secret handling entirely")
+	flags.BoolVar(&allowNoValue, "allow-no-value", false, "render templates even if they use undefined values")
+	flags.BoolVar(&strictMerge, "strict-class-merge", false, "fail if two classes contribute to the same key instead of deep-merging them")
+	flags.BoolVar(&strict, "strict", false, "fail on variables which do not point to any value and warn if a target changes the type of a value")
+	flags.Var(&allowed, "allow-variable", "glob pattern of variables which are allowed to be undefined in strict mode (e.g. 'HOME'), can be repeated or comma-separated")
+	flags.IntVar(&concurrency, "concurrency", runtime.NumCPU(), "maximum number of targets which are compiled at the same time")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	// targets can also be passed as positional arguments
+	for _, arg := range flags.Args() {
+		targets.Set(arg)
+	}
+
+	if revealSecrets && skipSecrets {
+		return fmt.Errorf("-reveal-secrets and -skip-secrets cannot be used together")
+	}
+
+	fileSystem := afero.NewOsFs()
+
+	explicitFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicitFlags[f.Name] = true
+	})
+
+	project, err := loadProject(fileSystem, projectPath, explicitFlags["project"])
+	if err != nil {
+		return err
+	}
+
+	// path flags overwrite the project configuration
+	if explicitFlags["inventory"] {
+		setInventoryPath(project, inventoryPath)
+	}
+	if explicitFlags["templates"] {
+		project.Templates = absPath(templatePath)
+	}
+	if explicitFlags["output"] {
+		project.Output = absPath(outputPath)
+		project.Targets = nil
+	}
+
+	// user template functions are Go functions, they can only be passed when skipper is used as a library
+	if len(project.Functions) > 0 {
+		return fmt.Errorf("project enables the template functions %s, user template functions are only available when skipper is used as a library", strings.Join(project.Functions, ", "))
+	}
+
+	secretMode := skipper.SecretsLoad
+	if revealSecrets {
+		secretMode = skipper.SecretsReveal
+	}
+	if skipSecrets {
+		secretMode = skipper.SecretsSkip
+	}
+
+	var dataOptions []skipper.DataOption
+	if strictMerge {
+		dataOptions = append(dataOptions, skipper.WithStrictClassMerge())
+	}
+	if strict {
+		dataOptions = append(dataOptions, skipper.WithStrictMode(allowed...))
+	}
+
+	result, err := skipper.Compile(context.Background(), skipper.CompileOptions{
+		Project:      project,
+		Targets:      targets,
+		SecretMode:   secretMode,
+		AllowNoValue: allowNoValue,
+		DataOptions:  dataOptions,
+		Concurrency:  concurrency,
+	})
+	if result != nil {
+		for _, target := range result.Targets {
+			for _, warning := range target.Warnings {
+				log.Printf("warning: target '%s': %s", target.Name, warning)
+			}
+			log.Printf("compiled target '%s' into '%s' (%d files) in %s", target.Name, target.OutputPath, len(target.Files), target.Duration)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("compiled %d target(s) in %s", len(result.Targets), result.Duration)
+
+	return nil
+}
+
+// loadProject loads the project file at projectPath if it exists or if it was set explicitly.
+// Otherwise the default project rooted at the working directory is returned.
+func loadProject(fileSystem afero.Fs, projectPath string, explicit bool) (*skipper.Project, error) {
+	if exists, _ := afero.Exists(fileSystem, projectPath); exists || explicit {
+		return skipper.LoadProject(fileSystem, projectPath)
+	}
+	return skipper.DefaultProject(fileSystem, "."), nil
+}
+
+// setInventoryPath overwrites the inventory paths of the project with the folders inside inventoryPath.
+func setInventoryPath(project *skipper.Project, inventoryPath string) {
+	project.Inventory.Classes = absPath(filepath.Join(inventoryPath, "classes"))
+	project.Inventory.Targets = absPath(filepath.Join(inventoryPath, "targets"))
+	project.Inventory.Secrets = absPath(filepath.Join(inventoryPath, "secrets"))
+}
+
+// absPath returns the absolute representation of path.
+// Flags are relative to the working directory and not to the project file.
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/spf13/afero"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func runDiff(args []string) error {
+	var (
+		projectPath   string
+		inventoryPath string
+		basePath      string
+		format        string
+	)
+
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: skipper diff [flags] <target> <other-target>")
+		fmt.Fprintln(flags.Output(), "       skipper diff [flags] -base <inventory> <target>")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Shows how the resolved data of two targets differs.")
+		fmt.Fprintln(flags.Output(), "With -base, the target is compared with the same target of another inventory, e.g. an older checkout.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&projectPath, "project", skipper.ProjectFileName, "path to the project file, it is only required to exist if the flag is set explicitly")
+	flags.StringVar(&inventoryPath, "inventory", "inventory", "path to the inventory folder which contains the 'classes', 'targets' and 'secrets' folders")
+	flags.StringVar(&basePath, "base", "", "path to the inventory folder to compare the target with")
+	flags.StringVar(&format, "format", "text", "output format: 'text', 'json' or 'yaml' (unified diff)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if format != "text" && format != "json" && format != "yaml" {
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+
+	var nameA, nameB string
+	switch {
+	case basePath == "" && flags.NArg() == 2:
+		nameA, nameB = flags.Arg(0), flags.Arg(1)
+	case basePath != "" && flags.NArg() == 1:
+		nameA, nameB = flags.Arg(0), flags.Arg(0)
+	default:
+		flags.Usage()
+		return fmt.Errorf("expected two targets, or one target and -base")
+	}
+
+	explicitFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicitFlags[f.Name] = true
+	})
+
+	project, err := loadProject(afero.NewOsFs(), projectPath, explicitFlags["project"])
+	if err != nil {
+		return err
+	}
+	if explicitFlags["inventory"] {
+		setInventoryPath(project, inventoryPath)
+	}
+
+	inventory, err := project.NewInventory()
+	if err != nil {
+		return err
+	}
+	var diff *skipper.TargetDiff
+	labelA, labelB := nameA, nameB
+	if basePath != "" {
+		baseProject := *project
+		setInventoryPath(&baseProject, basePath)
+		baseInventory, err := baseProject.NewInventory()
+		if err != nil {
+			return fmt.Errorf("failed to load base inventory: %w", err)
+		}
+		labelA = "base/" + nameA
+		diff, err = inventory.DiffBase(baseInventory, nameA, project.PredefinedVariables())
+		if err != nil {
+			return err
+		}
+	} else {
+		diff, err = inventory.DiffTargets(nameA, nameB, project.PredefinedVariables())
+		if err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case "json":
+		out, err := skipper.RenderDiffJSON(diff.Changes)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	case "yaml":
+		out, err := skipper.RenderDiffYAML(diff.From, diff.To, labelA, labelB)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+	default:
+		fmt.Print(skipper.RenderDiffText(diff.Changes))
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/spf13/afero"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func runExplain(args []string) error {
+	var (
+		projectPath   string
+		inventoryPath string
+	)
+
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: skipper explain [flags] <target> [path]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Explains where the values of the target at or below the path (e.g. 'network.subnets[0]') come from.")
+		fmt.Fprintln(flags.Output(), "Without a path, all values of the target are explained.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&projectPath, "project", skipper.ProjectFileName, "path to the project file, it is only required to exist if the flag is set explicitly")
+	flags.StringVar(&inventoryPath, "inventory", "inventory", "path to the inventory folder which contains the 'classes', 'targets' and 'secrets' folders")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return fmt.Errorf("expected a target and an optional path")
+	}
+	targetName, path := flags.Arg(0), flags.Arg(1)
+
+	explicitFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicitFlags[f.Name] = true
+	})
+
+	project, err := loadProject(afero.NewOsFs(), projectPath, explicitFlags["project"])
+	if err != nil {
+		return err
+	}
+	if explicitFlags["inventory"] {
+		setInventoryPath(project, inventoryPath)
+	}
+
+	inventory, err := project.NewInventory()
+	if err != nil {
+		return err
+	}
+
+	explained, err := inventory.Explain(targetName, path)
+	if err != nil {
+		return err
+	}
+	for _, value := range explained {
+		fmt.Print(value)
+	}
+
+	return nil
+}
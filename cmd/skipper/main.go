@@ -0,0 +1,84 @@
+// Command skipper is the command-line interface of Skipper.
+// It wraps the library into a single binary so that projects do not have to maintain their own main.go.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// command is a single subcommand of the skipper binary.
+type command struct {
+	Name        string
+	Description string
+	Run         func(args []string) error
+}
+
+var commands = []command{
+	{
+		Name:        "compile",
+		Description: "compile one, several or all targets of the inventory",
+		Run:         runCompile,
+	},
+	{
+		Name:        "explain",
+		Description: "explain where the values of a target come from",
+		Run:         runExplain,
+	},
+	{
+		Name:        "diff",
+		Description: "show how the resolved data of two targets differs",
+		Run:         runDiff,
+	},
+	{
+		Name:        "vendor",
+		Description: "fetch the vendor sources of the project and pin them in the lock file",
+		Run:         runVendor,
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "--help" || name == "help" {
+		printUsage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.Name != name {
+			continue
+		}
+
+		err := cmd.Run(os.Args[2:])
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipper %s: %s\n", cmd.Name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "skipper: unknown command '%s'\n\n", name)
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: skipper <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.Name, cmd.Description)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Use 'skipper <command> -h' for more information about a command.")
+}
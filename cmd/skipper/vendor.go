@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/spf13/afero"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func runVendor(args []string) error {
+	var (
+		projectPath string
+		update      bool
+	)
+
+	flags := flag.NewFlagSet("vendor", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: skipper vendor [flags]")
+		fmt.Fprintln(flags.Output())
+		fmt.Fprintln(flags.Output(), "Fetches the vendor sources of the project file into the vendor directory and pins them in '"+skipper.VendorLockFileName+"'.")
+		fmt.Fprintln(flags.Output(), "Sources which are already locked are fetched at the locked commit.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&projectPath, "project", skipper.ProjectFileName, "path to the project file")
+	flags.BoolVar(&update, "update", false, "resolve the refs of all sources again instead of using the locked commits")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", flags.Args())
+	}
+
+	project, err := skipper.LoadProject(afero.NewOsFs(), projectPath)
+	if err != nil {
+		return err
+	}
+
+	lock, err := project.FetchVendor(context.Background(), update)
+	if err != nil {
+		return err
+	}
+
+	for _, source := range lock.Sources {
+		log.Printf("vendored '%s' at %s", source.Name, source.Commit)
+	}
+	log.Printf("wrote %s", project.VendorLockPath())
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package skipper
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SecretMode defines how secrets are handled during compilation.
+type SecretMode int
+
+const (
+	// SecretsLoad loads (and if required creates) all secrets, but does not replace them.
+	SecretsLoad SecretMode = iota
+	// SecretsReveal replaces all secrets with their actual value.
+	// CAUTION: This is not something you want to do during local development, only inside your CI pipeline when the compiled output is ephemeral.
+	SecretsReveal
+	// SecretsSkip skips secret handling entirely.
+	SecretsSkip
+)
+
+// CompileOptions configure [Compile].
+type CompileOptions struct {
+	// Project describes where the inventory, the templates and the output are located.
+	Project *Project
+	// Inventory is the inventory to compile. If it is nil, the inventory is loaded using the Project.
+	Inventory *Inventory
+	// Targets are the names of the targets to compile. If empty, all targets are compiled.
+	Targets []string
+	// SecretMode defines how secrets are handled.
+	SecretMode SecretMode
+	// AllowNoValue renders templates even if they use values which are not defined.
+	AllowNoValue bool
+	// PredefinedVariables are passed to [Inventory.Data]. They take precedence over the variables of the Project.
+	PredefinedVariables map[string]interface{}
+	// TemplateFuncs are additional template functions. The Project decides which of them are enabled.
+	TemplateFuncs map[string]any
+	// DataOptions are passed to [Inventory.Data].
+	DataOptions []DataOption
+	// Concurrency is the maximum number of targets which are compiled at the same time.
+	// Values below 1 compile one target after another.
+	Concurrency int
+}
+
+// CompileResult is the outcome of [Compile].
+type CompileResult struct {
+	// Targets holds the result of every compiled target, in the order of compilation.
+	Targets []TargetResult
+	// Duration is the time it took to compile all targets.
+	Duration time.Duration
+}
+
+// TargetResult is the outcome of compiling a single target.
+type TargetResult struct {
+	// Name of the target.
+	Name string
+	// OutputPath is the output root of the target.
+	OutputPath string
+	// Files are the paths of all written files, relative to the OutputPath.
+	Files []string
+	// Duration is the time it took to compile the target.
+	Duration time.Duration
+	// Warnings are problems which did not stop the compilation, but should be looked at.
+	Warnings []string
+}
+
+// Compile runs the whole Skipper pipeline for the configured targets.
+// For every target the inventory data is loaded, the templates are rendered (as components if configured)
+// and finally the files are copied as configured by the target.
+// Up to [CompileOptions.Concurrency] targets are compiled at the same time.
+//
+// Compilation stops on the first error or if the context is cancelled.
+// The returned result always contains the targets which have been compiled so far, in the order of the targets.
+func Compile(ctx context.Context, opts CompileOptions) (*CompileResult, error) {
+	if opts.Project == nil {
+		return nil, fmt.Errorf("project cannot be nil")
+	}
+
+	start := time.Now()
+	result := &CompileResult{}
+
+	inventory := opts.Inventory
+	if inventory == nil {
+		var err error
+		inventory, err = opts.Project.NewInventory()
+		if err != nil {
+			return result, err
+		}
+	}
+
+	targets := opts.Targets
+	if len(targets) == 0 {
+		for _, target := range inventory.GetAllTargets() {
+			targets = append(targets, target.Name)
+		}
+		sort.Strings(targets)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(targets) {
+		concurrency = len(targets)
+	}
+
+	// the first failing target cancels all remaining ones
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results  = make([]*TargetResult, len(targets))
+		firstErr error
+		errMu    sync.Mutex
+		wg       sync.WaitGroup
+		jobs     = make(chan int)
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if workerCtx.Err() != nil {
+					continue
+				}
+
+				targetResult, err := compileTarget(workerCtx, inventory, targets[job], opts)
+				if err != nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to compile target '%s': %w", targets[job], err)
+					}
+					errMu.Unlock()
+					cancel()
+					continue
+				}
+				results[job] = targetResult
+			}
+		}()
+	}
+	for job := range targets {
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, targetResult := range results {
+		if targetResult != nil {
+			result.Targets = append(result.Targets, *targetResult)
+		}
+	}
+	result.Duration = time.Since(start)
+
+	if firstErr != nil {
+		return result, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// CompileAll compiles every target of the inventory, see [Compile].
+// The targets are compiled on a pool of [CompileOptions.Concurrency] workers,
+// which defaults to the number of CPUs. [CompileOptions.Targets] is ignored.
+func CompileAll(ctx context.Context, opts CompileOptions) (*CompileResult, error) {
+	opts.Targets = nil
+	if opts.Concurrency < 1 {
+		opts.Concurrency = runtime.NumCPU()
+	}
+	return Compile(ctx, opts)
+}
+
+// compileTarget compiles a single target, see [Compile].
+func compileTarget(ctx context.Context, inventory *Inventory, targetName string, opts CompileOptions) (*TargetResult, error) {
+	start := time.Now()
+	result := &TargetResult{
+		Name:       targetName,
+		OutputPath: opts.Project.OutputPath(targetName),
+	}
+
+	config, err := inventory.GetSkipperConfig(targetName)
+	if err != nil {
+		return nil, err
+	}
+
+	// user-defined variables have precedence over the project defaults
+	predefinedVariables := opts.Project.PredefinedVariables()
+	for key, value := range opts.PredefinedVariables {
+		predefinedVariables[key] = value
+	}
+
+	// warnings of the inventory are reported as warnings of the target
+	dataOptions := append(append([]DataOption{}, opts.DataOptions...), WithWarnings(&result.Warnings))
+	data, err := inventory.Data(targetName, predefinedVariables, opts.SecretMode == SecretsSkip, opts.SecretMode == SecretsReveal, dataOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	templater, err := opts.Project.NewTemplater(targetName, opts.TemplateFuncs, config.IgnoreRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	// report everything which references templates which do not exist, the templater would silently skip them
+	for _, component := range config.Components {
+		for _, input := range component.InputPaths {
+			if templater.getTemplateByPath(input) == nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("component input path '%s' does not match any template", input))
+			}
+		}
+	}
+	for _, rename := range config.Renames {
+		if templater.getTemplateByPath(rename.InputPath) == nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("rename input path '%s' does not match any template", rename.InputPath))
+		}
+	}
+
+	templateContext := DefaultTemplateContext(data, targetName)
+
+	// targets without components render every template
+	if len(config.Components) > 0 {
+		err = templater.ExecuteComponents(templateContext, config.Components, opts.AllowNoValue)
+	} else {
+		err = templater.ExecuteAll(templateContext, opts.AllowNoValue, config.Renames)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Files = append(result.Files, templater.WrittenFiles()...)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// copy files as specified in the target config (base path is template root)
+	err = CopyFilesByConfig(opts.Project.Fs(), config.Copies, opts.Project.TemplatePath(), result.OutputPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, copyConfig := range config.Copies {
+		result.Files = append(result.Files, filepath.Clean(copyConfig.TargetPath))
+	}
+
+	result.Duration = time.Since(start)
+
+	return result, nil
+}
@@ -0,0 +1,157 @@
+package skipper_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+// newTestFs returns a new in-memory filesystem which contains the given files.
+func newTestFs(t *testing.T, files map[string]string) afero.Fs {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	for path, content := range files {
+		require.NoError(t, skipper.WriteFile(fs, path, []byte(content), 0644))
+	}
+	return fs
+}
+
+var compileTestFiles = map[string]string{
+	"/inventory/classes/project.yaml": `
+project:
+  name: example
+  owner: ${owner}
+`,
+	"/inventory/targets/dev.yaml": `
+target:
+  skipper:
+    use: [project]
+    components:
+      - output_path: docs
+        input_paths: [README.md, missing.md]
+        rename:
+          - input_path: README.md
+            filename: README_dev.md
+    copy:
+      - source: static.txt
+        target: static/copied.txt
+`,
+	"/inventory/targets/prod.yaml": `
+target:
+  skipper:
+    use: [project]
+  project:
+    name: production
+`,
+	"/inventory/secrets/.gitkeep": "",
+	"/templates/README.md":        `{{ .Inventory.project.name }} by {{ .Inventory.project.owner }} ({{ .TargetName }}) {{ shout }}`,
+	"/templates/static.txt":       `static`,
+}
+
+func TestCompile(t *testing.T) {
+	fs := newTestFs(t, compileTestFiles)
+
+	result, err := skipper.Compile(context.Background(), skipper.CompileOptions{
+		Project:             skipper.DefaultProject(fs, "/"),
+		PredefinedVariables: map[string]interface{}{"owner": "AcmeCorp"},
+		TemplateFuncs:       map[string]any{"shout": func() string { return "!" }},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Targets, 2)
+
+	dev := result.Targets[0]
+	assert.Equal(t, "dev", dev.Name)
+	assert.Equal(t, "/compiled/dev", dev.OutputPath)
+	assert.Equal(t, []string{"docs/README_dev.md", "static/copied.txt"}, dev.Files)
+	assert.Len(t, dev.Warnings, 1)
+
+	prod := result.Targets[1]
+	assert.Equal(t, "prod", prod.Name)
+	assert.ElementsMatch(t, []string{"README.md", "static.txt"}, prod.Files)
+	assert.Empty(t, prod.Warnings)
+
+	out, err := afero.ReadFile(fs, "/compiled/dev/docs/README_dev.md")
+	require.NoError(t, err)
+	assert.Equal(t, "example by AcmeCorp (dev) !", string(out))
+
+	out, err = afero.ReadFile(fs, "/compiled/prod/README.md")
+	require.NoError(t, err)
+	assert.Equal(t, "production by AcmeCorp (prod) !", string(out))
+
+	exists, _ := afero.Exists(fs, "/compiled/dev/static/copied.txt")
+	assert.True(t, exists)
+}
+
+func TestCompileSelectedTargets(t *testing.T) {
+	fs := newTestFs(t, compileTestFiles)
+
+	result, err := skipper.Compile(context.Background(), skipper.CompileOptions{
+		Project:             skipper.DefaultProject(fs, "/"),
+		Targets:             []string{"prod"},
+		PredefinedVariables: map[string]interface{}{"owner": "AcmeCorp"},
+		TemplateFuncs:       map[string]any{"shout": func() string { return "!" }},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Targets, 1)
+	assert.Equal(t, "prod", result.Targets[0].Name)
+
+	exists, _ := afero.DirExists(fs, "/compiled/dev")
+	assert.False(t, exists)
+
+	_, err = skipper.Compile(context.Background(), skipper.CompileOptions{
+		Project: skipper.DefaultProject(fs, "/"),
+		Targets: []string{"staging"},
+	})
+	assert.Error(t, err)
+}
+
+func TestCompileCancelled(t *testing.T) {
+	fs := newTestFs(t, compileTestFiles)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := skipper.Compile(ctx, skipper.CompileOptions{
+		Project: skipper.DefaultProject(fs, "/"),
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, result.Targets)
+}
+
+func TestCompileAll(t *testing.T) {
+	files := map[string]string{
+		"/inventory/classes/project.yaml": "project:\n  name: ${target_name}\n  tags: ['${target_name}']\n",
+		"/inventory/secrets/.gitkeep":     "",
+		"/templates/name.txt":             `{{ .Inventory.project.name }} {{ index .Inventory.project.tags 0 }}`,
+	}
+	var targets []string
+	for i := 0; i < 16; i++ {
+		name := fmt.Sprintf("target%02d", i)
+		targets = append(targets, name)
+		files[fmt.Sprintf("/inventory/targets/%s.yaml", name)] = "target:\n  skipper:\n    use: [project]\n"
+	}
+	fs := newTestFs(t, files)
+
+	result, err := skipper.CompileAll(context.Background(), skipper.CompileOptions{
+		Project:     skipper.DefaultProject(fs, "/"),
+		Targets:     []string{"target00"},
+		Concurrency: 4,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Targets, len(targets))
+
+	for i, name := range targets {
+		assert.Equal(t, name, result.Targets[i].Name)
+
+		out, err := afero.ReadFile(fs, fmt.Sprintf("/compiled/%s/name.txt", name))
+		require.NoError(t, err)
+		assert.Equal(t, name+" "+name, string(out))
+	}
+}
@@ -2,8 +2,7 @@ package skipper
 
 import (
 	"fmt"
-	"reflect"
-	"strconv"
+	"sort"
 
 	"gopkg.in/yaml.v3"
 )
@@ -51,6 +50,16 @@ func (d Data) HasKey(key string) bool {
 	return false
 }
 
+// sortedKeys returns all keys of the Data in lexical order.
+func (d Data) sortedKeys() []string {
+	keys := make([]string, 0, len(d))
+	for key := range d {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Get returns the value at `Data[key]` as [Data].
 // Note that his function does not support paths like `HasKey("foo.bar.baz")`.
 // For that you can use [GetPath]
@@ -68,6 +77,9 @@ func (d Data) Get(key string) Data {
 //   - ["foo", "bar"]
 //   - ["foo", "bar", 0]
 func (d Data) GetPath(path ...interface{}) (tree interface{}, err error) {
+	if hasWildcard(path) {
+		return nil, fmt.Errorf("path '%s' contains wildcards, use GetAll instead", FormatPath(path))
+	}
 	tree = d
 
 	for i, el := range path {
@@ -79,7 +91,7 @@ func (d Data) GetPath(path ...interface{}) (tree interface{}, err error) {
 			}
 			tree, ok = node[key]
 			if !ok {
-				return nil, fmt.Errorf("key not found: %v", el)
+				return nil, &KeyNotFoundError{Key: el, Path: append([]interface{}{}, path[:i+1]...)}
 			}
 
 		case map[string]interface{}:
@@ -89,26 +101,20 @@ func (d Data) GetPath(path ...interface{}) (tree interface{}, err error) {
 			}
 			tree, ok = node[key]
 			if !ok {
-				return nil, fmt.Errorf("key not found: %v", el)
+				return nil, &KeyNotFoundError{Key: el, Path: append([]interface{}{}, path[:i+1]...)}
 			}
 
 		case map[interface{}]interface{}:
 			var ok bool
 			tree, ok = node[el]
 			if !ok {
-				return nil, fmt.Errorf("key not found: %v", el)
+				return nil, &KeyNotFoundError{Key: el, Path: append([]interface{}{}, path[:i+1]...)}
 			}
 
 		case []interface{}:
-			index, ok := el.(int)
-			if !ok {
-				index, err = strconv.Atoi(fmt.Sprint(el))
-				if err != nil {
-					return nil, fmt.Errorf("unexpected integer path element '%v' (%T)", el, el)
-				}
-			}
-			if index < 0 || index >= len(node) {
-				return nil, fmt.Errorf("path index out of range: %d", index)
+			index, err := listIndex(el, len(node))
+			if err != nil {
+				return nil, err
 			}
 			tree = node[index]
 
@@ -119,28 +125,46 @@ func (d Data) GetPath(path ...interface{}) (tree interface{}, err error) {
 	return tree, nil
 }
 
+// SetPathOption configures [Data.SetPathWith].
+type SetPathOption func(*setPathOptions)
+
+type setPathOptions struct {
+	createMissing bool
+}
+
+// WithCreateMissing creates all missing nodes of the path instead of failing.
+// Missing keys are created as maps, or as lists if the next segment of the path is an index.
+// Indices beyond the end of a list grow the list, the new slots are nil. Existing values which are neither
+// a map nor a list are never replaced.
+func WithCreateMissing() SetPathOption {
+	return func(o *setPathOptions) {
+		o.createMissing = true
+	}
+}
+
 // SetPath uses the same path slices as [GetPath], only that it can set the value at the given path.
-// Supports array indexing (arrays start at 0)
+// Supports array indexing (arrays start at 0, negative indices count from the end).
+// All nodes of the path except for the last one must exist, see [Data.SetPathWith] to create them.
 func (d *Data) SetPath(value interface{}, path ...interface{}) (err error) {
-	var tree interface{}
-	tree = (*d)
+	return d.SetPathWith(value, path)
+}
+
+// SetPathWith works like [Data.SetPath], but it can be configured with options, e.g. [WithCreateMissing].
+func (d *Data) SetPathWith(value interface{}, path []interface{}, opts ...SetPathOption) error {
+	options := new(setPathOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
 
 	if len(path) == 0 {
 		return fmt.Errorf("path cannot be empty")
 	}
-
-	// attempt to get the requested path if there is more than one path segment
-	// TODO: if the path does not exist, create it
-	i := len(path) - 1
-	if len(path) > 1 {
-		var tmp interface{}
-		tmp, err = tree.(Data).GetPath(path[:i]...)
-		if err != nil {
-			return err
-		}
-		tree = tmp
+	if hasWildcard(path) {
+		return fmt.Errorf("path '%s' contains wildcards", FormatPath(path))
+	}
+	if *d == nil {
+		*d = make(Data)
 	}
-	element := path[i]
 
 	// we have no idea what [value] is, but we can make sure that
 	// any yaml structtags are respected by shoving it through the yaml package.
@@ -156,69 +180,220 @@ func (d *Data) SetPath(value interface{}, path ...interface{}) (err error) {
 		}
 	}
 
-	switch node := tree.(type) {
-	case Data:
+	_, err := setValue(*d, path, 0, value, options.createMissing)
+	return err
+}
+
+// setValue sets the value at path[i:] inside the node and returns the node.
+// The returned node differs from the given one if it had to be created or if a list has grown,
+// in that case the caller has to replace the node inside its parent.
+func setValue(node interface{}, path []interface{}, i int, value interface{}, create bool) (interface{}, error) {
+	element := path[i]
+	last := i == len(path)-1
+
+	// missing nodes are created depending on the type of the path element
+	if node == nil && create {
+		if _, isIndex := element.(int); isIndex {
+			node = []interface{}{}
+		} else {
+			node = make(Data)
+		}
+	}
+
+	switch n := node.(type) {
+	case Data, map[string]interface{}:
+		nodeMap, _ := asData(n)
 		key, ok := element.(string)
 		if !ok {
-			return fmt.Errorf("unexpected string key in map[string]interface '%T' at index %d", element, i)
+			return nil, fmt.Errorf("unexpected string key in map[string]interface '%T' at index %d", element, i)
+		}
+		if last {
+			nodeMap[key] = value
+			return node, nil
+		}
+		child, exists := nodeMap[key]
+		if !exists && !create {
+			return nil, &KeyNotFoundError{Key: element, Path: append([]interface{}{}, path[:i+1]...)}
 		}
-		node[key] = value
+		child, err := setValue(child, path, i+1, value, create)
+		if err != nil {
+			return nil, err
+		}
+		nodeMap[key] = child
+		return node, nil
 
-	case map[string]interface{}:
+	case []interface{}:
+		index, err := listIndex(element, len(n))
+		if err != nil {
+			// with create, the list grows until the index exists
+			requested, isIndex := element.(int)
+			if !create || !isIndex || requested < len(n) {
+				return nil, err
+			}
+			n = append(n, make([]interface{}, requested-len(n)+1)...)
+			index = requested
+		}
+		if last {
+			n[index] = value
+			return n, nil
+		}
+		child, err := setValue(n[index], path, i+1, value, create)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected node type %T at index %d", node, i)
+	}
+}
+
+// DeletePath removes the value at the given path and returns it.
+// Removing a list item shifts all following items of the list.
+func (d Data) DeletePath(path ...interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("path cannot be empty")
+	}
+	if hasWildcard(path) {
+		return nil, fmt.Errorf("path '%s' contains wildcards", FormatPath(path))
+	}
+
+	_, removed, err := deleteValue(d, path, 0)
+	return removed, err
+}
+
+// deleteValue removes the value at path[i:] from the node and returns the node and the removed value.
+// Lists are shrunk, hence the caller has to replace the returned node inside its parent.
+func deleteValue(node interface{}, path []interface{}, i int) (interface{}, interface{}, error) {
+	element := path[i]
+	last := i == len(path)-1
+
+	switch n := node.(type) {
+	case Data, map[string]interface{}:
+		nodeMap, _ := asData(n)
 		key, ok := element.(string)
 		if !ok {
-			return fmt.Errorf("unexpected string key in map[string]interface '%T' at index %d", element, i)
+			return nil, nil, fmt.Errorf("unexpected string key in map[string]interface '%T'", element)
 		}
-		node[key] = value
+		child, exists := nodeMap[key]
+		if !exists {
+			return nil, nil, &KeyNotFoundError{Key: element, Path: append([]interface{}{}, path[:i+1]...)}
+		}
+		if last {
+			delete(nodeMap, key)
+			return node, child, nil
+		}
+		child, removed, err := deleteValue(child, path, i+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		nodeMap[key] = child
+		return node, removed, nil
 
 	case []interface{}:
-		index, ok := element.(int)
-		if !ok {
-			index, err = strconv.Atoi(fmt.Sprint(element))
-			if err != nil {
-				return fmt.Errorf("unexpected integer path element '%v (%T)'", element, element)
-			}
+		index, err := listIndex(element, len(n))
+		if err != nil {
+			return nil, nil, err
+		}
+		if last {
+			removed := n[index]
+			return append(append([]interface{}{}, n[:index]...), n[index+1:]...), removed, nil
 		}
-		if index < 0 || index >= len(node) {
-			return fmt.Errorf("path index out of range: %d", index)
+		child, removed, err := deleteValue(n[index], path, i+1)
+		if err != nil {
+			return nil, nil, err
 		}
-		node[index] = value
+		n[index] = child
+		return n, removed, nil
 
 	default:
-		return fmt.Errorf("unexpected node type %T at index %d", node, i)
+		return nil, nil, fmt.Errorf("unexpected node type %T at index %d", node, i)
 	}
+}
 
-	return nil
+// CopyPath copies the value at the path from to the path to, see [Data.GetPath].
+// The value is deep-copied and an existing value at the destination is replaced. Missing nodes of the destination are created.
+func (d *Data) CopyPath(from, to []interface{}) error {
+	value, err := d.GetPath(from...)
+	if err != nil {
+		return err
+	}
+	return d.SetPathWith(copyValue(value), to, WithCreateMissing())
+}
+
+// MovePath moves the value at the path from to the path to, see [Data.CopyPath].
+// The destination must not be inside the moved value.
+func (d *Data) MovePath(from, to []interface{}) error {
+	if hasPathPrefix(to, from) {
+		return fmt.Errorf("cannot move '%s' into itself: %s", FormatPath(from), FormatPath(to))
+	}
+
+	// the move is tried on a copy first, otherwise a failing destination would lose the value
+	move := func(data *Data) error {
+		value, err := data.DeletePath(from...)
+		if err != nil {
+			return err
+		}
+		return data.SetPathWith(value, to, WithCreateMissing())
+	}
+	probe := d.Copy()
+	if err := move(&probe); err != nil {
+		return err
+	}
+	return move(d)
 }
 
 // MergeReplace merges the existing Data with the given.
 // If a key already exists, the passed data has precedence and it's value will be used.
+// Maps are merged recursively and lists are appended, unless the key of the passed data carries a merge directive:
+//
+//	foo::replace: [...]    replace the existing value instead of merging it
+//	foo::append: [...]     append to the existing list (default)
+//	foo::prepend: [...]    prepend to the existing list
+//	foo::merge=id: [...]   merge the list of maps by the 'id' field of the items (`::merge` uses 'name'), other items are appended
+//	foo::delete: ~         remove the existing value
+//
+// Any other key is used as it is, a directive is escaped with a backslash (`foo\::replace`).
+// The directives can also be set with the tags `!replace`, `!append`, `!prepend`, `!merge` (by 'name') and `!delete`
+// in inventory files. The directives are removed from the returned Data.
 func (d Data) MergeReplace(data Data) Data {
-	out := make(Data, len(d))
-	for k, v := range d {
-		out[k] = v
+	return mergeData(d, data, "", nil)
+}
+
+// Copy returns a deep copy of the Data.
+// Nested maps and lists are copied as well, hence modifying the copy never affects the original.
+func (d Data) Copy() Data {
+	if d == nil {
+		return nil
 	}
-	for k, v := range data {
-		if v, ok := v.(Data); ok {
-			if bv, ok := out[k]; ok {
-				if bv, ok := bv.(Data); ok {
-					out[k] = bv.MergeReplace(v)
-					continue
-				}
-			}
+	return copyValue(d).(Data)
+}
+
+// copyValue returns a deep copy of maps and lists, any other value is returned as is.
+func copyValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case Data:
+		out := make(Data, len(typed))
+		for k, v := range typed {
+			out[k] = copyValue(v)
 		}
-		if v, ok := v.([]interface{}); ok {
-			if bv, ok := out[k]; ok {
-				if bv, ok := bv.([]interface{}); ok {
-					out[k] = append(bv, v...)
-					continue
-				}
-			}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			out[k] = copyValue(v)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(typed))
+		for i, v := range typed {
+			out[i] = copyValue(v)
 		}
-		out[k] = v
+		return out
+	default:
+		return value
 	}
-
-	return out
 }
 
 // FindValueFunc is a callback used to find values inside a Data map.
@@ -227,59 +402,23 @@ func (d Data) MergeReplace(data Data) Data {
 type FindValueFunc func(value string, path []interface{}) (interface{}, error)
 
 // FindValues can be used to find specific 'leaf' nodes, aka values.
-// The Data is iterated recursively and once a plain value is found, the given FindValueFunc is called.
+// The Data is walked (see [Data.Walk]) and once a plain value is found, the given FindValueFunc is called
+// with the string representation of the value. Nil values are skipped.
 // It's the responsibility of the FindValueFunc to determine if the value is what is searched for.
 // The FindValueFunc can return any data, which is aggregated and written into the passed `*[]interface{}`.
 // The callee is then responsible of handling the returned value and ensuring the correct types were returned.
 func (d Data) FindValues(valueFunc FindValueFunc, target *[]interface{}) (err error) {
-	// newPath is used to copy an existing []interface and hard-copy it.
-	// This is required because Go wants to optimize slice usage by reusing memory.
-	// Most of the time, this is totally fine, but in this case it would mess up the slice
-	// by changing the path []interface of already found secrets.
-	newPath := func(path []interface{}, appendValue interface{}) []interface{} {
-		tmp := make([]interface{}, len(path))
-		copy(tmp, path)
-		tmp = append(tmp, appendValue)
-		return tmp
-	}
-
-	var walk func(reflect.Value, []interface{}) error
-	walk = func(v reflect.Value, path []interface{}) error {
-		// fix indirects through pointers and interfaces
-		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
-			v = v.Elem()
+	return d.Walk(func(node *WalkNode) error {
+		switch node.Value.(type) {
+		case Data, map[string]interface{}, []interface{}, nil:
+			return nil
 		}
 
-		switch v.Kind() {
-		case reflect.Array, reflect.Slice:
-			for i := 0; i < v.Len(); i++ {
-				err := walk(v.Index(i), newPath(path, i))
-				if err != nil {
-					return err
-				}
-			}
-		case reflect.Map:
-			for _, key := range v.MapKeys() {
-				if v.MapIndex(key).IsNil() {
-					continue
-				}
-
-				err := walk(v.MapIndex(key), newPath(path, key.String()))
-				if err != nil {
-					return err
-				}
-			}
-		default:
-			// at this point we have found a value and give off control to the given valueFunc
-			value, err := valueFunc(v.String(), path)
-			if err != nil {
-				return err
-			}
-			(*target) = append(*target, value)
+		value, err := valueFunc(fmt.Sprint(node.Value), node.Path)
+		if err != nil {
+			return err
 		}
+		(*target) = append(*target, value)
 		return nil
-	}
-	err = walk(reflect.ValueOf(d), nil)
-
-	return err
+	})
 }
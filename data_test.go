@@ -0,0 +1,102 @@
+package skipper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestDataSetPathCreateMissing(t *testing.T) {
+	data := skipper.Data{
+		"plain":  map[string]interface{}{"list": []interface{}{"a"}},
+		"scalar": "value",
+	}
+
+	// without the option, missing nodes are not created
+	err := data.SetPath("x", "foo", "bar")
+	var keyErr *skipper.KeyNotFoundError
+	require.True(t, errors.As(err, &keyErr))
+	assert.Equal(t, []interface{}{"foo"}, keyErr.Path)
+
+	err = data.SetPathWith("x", []interface{}{"foo", "bar", 1, "baz"}, skipper.WithCreateMissing())
+	require.NoError(t, err)
+	assert.Equal(t, skipper.Data{"bar": []interface{}{nil, skipper.Data{"baz": "x"}}}, data["foo"])
+
+	// lists inside plain maps grow as well
+	err = data.SetPathWith("c", []interface{}{"plain", "list", 2}, skipper.WithCreateMissing())
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", nil, "c"}, data["plain"].(map[string]interface{})["list"])
+
+	err = data.SetPathWith("x", []interface{}{"scalar", "foo"}, skipper.WithCreateMissing())
+	assert.EqualError(t, err, "unexpected node type string at index 1")
+	err = data.SetPathWith("x", []interface{}{"plain", "list", -4}, skipper.WithCreateMissing())
+	assert.EqualError(t, err, "path index out of range: -4")
+
+	var empty skipper.Data
+	err = empty.SetPathWith(1, []interface{}{"a", "b"}, skipper.WithCreateMissing())
+	require.NoError(t, err)
+	assert.Equal(t, skipper.Data{"a": skipper.Data{"b": 1}}, empty)
+}
+
+func TestDataDeletePath(t *testing.T) {
+	data := skipper.Data{
+		"foo": map[string]interface{}{
+			"list": []interface{}{"a", []interface{}{"b", "c"}, "d"},
+		},
+	}
+
+	removed, err := data.DeletePath("foo", "list", 1, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "c", removed)
+	assert.Equal(t, []interface{}{"a", []interface{}{"b"}, "d"}, data["foo"].(map[string]interface{})["list"])
+
+	removed, err = data.DeletePath("foo", "list", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "a", removed)
+	assert.Equal(t, []interface{}{[]interface{}{"b"}, "d"}, data["foo"].(map[string]interface{})["list"])
+
+	_, err = data.DeletePath("foo", "missing")
+	var keyErr *skipper.KeyNotFoundError
+	assert.True(t, errors.As(err, &keyErr))
+
+	removed, err = data.DeletePath("foo")
+	require.NoError(t, err)
+	assert.NotNil(t, removed)
+	assert.Empty(t, data)
+}
+
+func TestDataCopyAndMovePath(t *testing.T) {
+	data := skipper.Data{
+		"source": skipper.Data{"list": []interface{}{"a", "b"}},
+	}
+
+	err := data.CopyPath([]interface{}{"source"}, []interface{}{"copy", "nested"})
+	require.NoError(t, err)
+	copied, err := data.GetPath("copy", "nested", "list", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "a", copied)
+
+	// the copy does not share the list with the source
+	err = data.SetPath("changed", "copy", "nested", "list", 0)
+	require.NoError(t, err)
+	original, err := data.GetPath("source", "list", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "a", original)
+
+	err = data.MovePath([]interface{}{"source", "list", 1}, []interface{}{"moved"})
+	require.NoError(t, err)
+	assert.Equal(t, "b", data["moved"])
+	assert.Equal(t, []interface{}{"a"}, data["source"].(skipper.Data)["list"])
+
+	err = data.MovePath([]interface{}{"source"}, []interface{}{"source", "inner"})
+	assert.EqualError(t, err, "cannot move 'source' into itself: source.inner")
+
+	// a destination which cannot be set keeps the source untouched
+	err = data.MovePath([]interface{}{"source"}, []interface{}{"moved", "inner"})
+	assert.Error(t, err)
+	assert.Contains(t, data, "source")
+}
@@ -0,0 +1,188 @@
+package skipper
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder decodes the raw content of an inventory file into a map.
+// The result is normalised into [Data] by the caller, hence a Decoder can return any
+// structure which can be marshalled as yaml.
+type Decoder func(in []byte) (map[string]interface{}, error)
+
+var (
+	decoders = map[string]Decoder{
+		"":      decodeYaml,
+		".yml":  decodeYaml,
+		".yaml": decodeYaml,
+		".json": decodeJson,
+		".toml": decodeToml,
+		".hcl":  decodeHcl,
+	}
+	decodersMu sync.RWMutex
+)
+
+// RegisterDecoder registers the decoder for files with the given extension (e.g. `.ini`).
+// An existing decoder for the extension is replaced.
+// All files with a registered extension are discovered as inventory files.
+func RegisterDecoder(extension string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(extension)] = decoder
+}
+
+// DecoderFor returns the decoder which is registered for the extension of the given path.
+func DecoderFor(path string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	decoder, ok := decoders[strings.ToLower(filepath.Ext(path))]
+	return decoder, ok
+}
+
+// SupportedExtensions returns all file extensions for which a decoder is registered, sorted alphabetically.
+func SupportedExtensions() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	extensions := make([]string, 0, len(decoders))
+	for extension := range decoders {
+		extensions = append(extensions, extension)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// decodeFile decodes the content of the file at path using the registered decoder and normalises the result.
+// Normalising ensures that the Data looks the same, regardless of the format:
+// nested maps are [Data], lists are []interface{} and numbers are int or float64.
+func decodeFile(path string, in []byte) (Data, error) {
+	decoder, ok := DecoderFor(path)
+	if !ok {
+		return nil, fmt.Errorf("no decoder registered for file extension '%s'", filepath.Ext(path))
+	}
+
+	decoded, err := decoder(in)
+	if err != nil {
+		return nil, err
+	}
+	if decoded == nil {
+		return nil, nil
+	}
+
+	return NewData(decoded)
+}
+
+// decodeYaml decodes yaml and applies the merge tags (e.g. `!replace`), see [Data.MergeReplace],
+// as well as the `!required` tags.
+func decodeYaml(in []byte) (map[string]interface{}, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(in, &node); err != nil {
+		return nil, err
+	}
+	applyMergeTags(&node)
+	applyRequiredTags(&node)
+
+	var out map[string]interface{}
+	if err := node.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeJson(in []byte) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	if err := json.Unmarshal(in, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeToml(in []byte) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	if err := toml.Unmarshal(in, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeHcl decodes HCL (version 1).
+// Blocks are decoded as nested maps, where every label adds a level: `resource "a" "b" { ... }` results in `resource.a.b`.
+// Blocks with the same keys are merged.
+func decodeHcl(in []byte) (out map[string]interface{}, err error) {
+	file, err := hcl.ParseBytes(in)
+	if err != nil {
+		return nil, err
+	}
+
+	// the hcl token conversion panics on malformed values
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid hcl value: %v", r)
+		}
+	}()
+
+	list, ok := file.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("hcl file does not contain an object")
+	}
+	return hclObject(list), nil
+}
+
+// hclObject converts the hcl object list into a map.
+func hclObject(list *ast.ObjectList) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, item := range list.Items {
+		value := hclValue(item.Val)
+		for i := len(item.Keys) - 1; i > 0; i-- {
+			value = map[string]interface{}{fmt.Sprint(item.Keys[i].Token.Value()): value}
+		}
+
+		key := fmt.Sprint(item.Keys[0].Token.Value())
+		out[key] = mergeHclValues(out[key], value)
+	}
+	return out
+}
+
+// hclValue converts any hcl value node.
+func hclValue(node ast.Node) interface{} {
+	switch typed := node.(type) {
+	case *ast.LiteralType:
+		return typed.Token.Value()
+	case *ast.ListType:
+		out := make([]interface{}, 0, len(typed.List))
+		for _, item := range typed.List {
+			out = append(out, hclValue(item))
+		}
+		return out
+	case *ast.ObjectType:
+		return hclObject(typed.List)
+	default:
+		return nil
+	}
+}
+
+// mergeHclValues merges blocks which share the same keys, any other value is overwritten.
+func mergeHclValues(existing, value interface{}) interface{} {
+	existingMap, ok := existing.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	for key, v := range valueMap {
+		existingMap[key] = mergeHclValues(existingMap[key], v)
+	}
+	return existingMap
+}
@@ -0,0 +1,91 @@
+package skipper_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestInventoryFileFormats(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/network.json": `{"network": {"cidr": "10.0.0.0/8", "ports": [80, 443], "ratio": 1.5, "secret": "?{plain:password.json}"}}`,
+		"classes/tags.toml": `
+[tags]
+owner = "platform"
+replicas = 3
+`,
+		"classes/cluster.hcl": `
+cluster {
+  name = "${target_name}"
+  node_pool "system" {
+    size = 2
+  }
+  node_pool "user" {
+    size = 5
+  }
+}
+`,
+		"classes/common.yml": "common:\n  name: common\n",
+		"targets/dev.toml": `
+[target.skipper]
+use = ["network", "tags", "cluster", "common"]
+
+[target.tags]
+owner = "dev-team"
+`,
+		"secrets/password.json": `{"data": "secret", "type": "plain", "key": ""}`,
+	})
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", nil, false, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, skipper.Data{
+		"cidr":   "10.0.0.0/8",
+		"ports":  []interface{}{80, 443},
+		"ratio":  1.5,
+		"secret": "secret",
+	}, data["network"])
+	assert.Equal(t, skipper.Data{"owner": "dev-team", "replicas": 3}, data["tags"])
+	assert.Equal(t, skipper.Data{
+		"name": "dev",
+		"node_pool": skipper.Data{
+			"system": skipper.Data{"size": 2},
+			"user":   skipper.Data{"size": 5},
+		},
+	}, data["cluster"])
+	assert.Equal(t, skipper.Data{"name": "common"}, data["common"])
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	_, ok := skipper.DecoderFor("class.custom")
+	assert.False(t, ok)
+	assert.Contains(t, skipper.SupportedExtensions(), ".toml")
+
+	skipper.RestoreDecoder(t, ".custom")
+	skipper.RegisterDecoder(".custom", func(in []byte) (map[string]interface{}, error) {
+		return map[string]interface{}{"custom": map[string]interface{}{"content": string(in)}}, nil
+	})
+
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/custom.custom": "hello",
+		"targets/dev.yaml":      "target:\n  skipper:\n    use: [custom]\n",
+	})
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, skipper.Data{"content": "hello"}, data["custom"])
+}
+
+func TestRegisterDecoderRestored(t *testing.T) {
+	t.Run("register", func(t *testing.T) {
+		skipper.RestoreDecoder(t, ".custom")
+		skipper.RegisterDecoder(".custom", func(in []byte) (map[string]interface{}, error) { return nil, nil })
+		assert.Contains(t, skipper.SupportedExtensions(), ".custom")
+	})
+	assert.NotContains(t, skipper.SupportedExtensions(), ".custom")
+}
@@ -0,0 +1,423 @@
+package skipper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeType describes how a value differs between two [Data] trees.
+type ChangeType string
+
+const (
+	// ChangeAdded is a value which only exists in the other Data.
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved is a value which only exists in the original Data.
+	ChangeRemoved ChangeType = "removed"
+	// ChangeModified is a value which differs, but is of the same kind (e.g. both are strings).
+	ChangeModified ChangeType = "modified"
+	// ChangeTypeChanged is a value which is of a different kind (e.g. a map became a string).
+	ChangeTypeChanged ChangeType = "type-changed"
+)
+
+// Change is a single difference between two [Data] trees, see [Data.Diff].
+type Change struct {
+	Type ChangeType
+	// Path of the value, see [FormatPath].
+	Path []interface{}
+	// From is the original value, it is nil if the value has been added.
+	From interface{}
+	// To is the new value, it is nil if the value has been removed.
+	To interface{}
+}
+
+// String returns the change as a single line, e.g. `~ network.cidr: "10.0.0.0/8" -> "10.1.0.0/16"`.
+func (c Change) String() string {
+	path := FormatPath(c.Path)
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", path, compactValue(c.To))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", path, compactValue(c.From))
+	case ChangeTypeChanged:
+		return fmt.Sprintf("! %s: %s (%s) -> %s (%s)", path, compactValue(c.From), valueKind(c.From), compactValue(c.To), valueKind(c.To))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", path, compactValue(c.From), compactValue(c.To))
+	}
+}
+
+// MarshalJSON encodes the change with its formatted path.
+// `from` is set unless the value has been added and `to` is set unless the value has been removed,
+// hence null values are encoded explicitly.
+func (c Change) MarshalJSON() ([]byte, error) {
+	var from, to *interface{}
+	if c.Type != ChangeAdded {
+		from = &c.From
+	}
+	if c.Type != ChangeRemoved {
+		to = &c.To
+	}
+
+	return json.Marshal(struct {
+		Type ChangeType   `json:"type"`
+		Path string       `json:"path"`
+		From *interface{} `json:"from,omitempty"`
+		To   *interface{} `json:"to,omitempty"`
+	}{
+		Type: c.Type,
+		Path: FormatPath(c.Path),
+		From: from,
+		To:   to,
+	})
+}
+
+// compactValue returns the value as compact JSON, or as plain string if it cannot be encoded.
+func compactValue(value interface{}) string {
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(out)
+}
+
+// Diff returns all differences between the Data and the other Data, ordered by path (keys are sorted).
+// Maps and lists are compared recursively, list items are compared by their index.
+// If a map or list is replaced by a value of another kind, only the type change is reported.
+func (d Data) Diff(other Data) []Change {
+	var changes []Change
+	diffValues(d, other, nil, &changes)
+	return changes
+}
+
+func diffValues(from, to interface{}, path []interface{}, changes *[]Change) {
+	fromMap, fromIsMap := asData(from)
+	toMap, toIsMap := asData(to)
+	if fromIsMap && toIsMap {
+		keys := fromMap.sortedKeys()
+		for _, key := range toMap.sortedKeys() {
+			if _, inFrom := fromMap[key]; !inFrom {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fromValue, inFrom := fromMap[key]
+			toValue, inTo := toMap[key]
+			keyPath := appendPath(path, key)
+			switch {
+			case !inFrom:
+				*changes = append(*changes, Change{Type: ChangeAdded, Path: keyPath, To: toValue})
+			case !inTo:
+				*changes = append(*changes, Change{Type: ChangeRemoved, Path: keyPath, From: fromValue})
+			default:
+				diffValues(fromValue, toValue, keyPath, changes)
+			}
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]interface{})
+	toList, toIsList := to.([]interface{})
+	if fromIsList && toIsList {
+		for i := 0; i < len(fromList) || i < len(toList); i++ {
+			itemPath := appendPath(path, i)
+			switch {
+			case i >= len(fromList):
+				*changes = append(*changes, Change{Type: ChangeAdded, Path: itemPath, To: toList[i]})
+			case i >= len(toList):
+				*changes = append(*changes, Change{Type: ChangeRemoved, Path: itemPath, From: fromList[i]})
+			default:
+				diffValues(fromList[i], toList[i], itemPath, changes)
+			}
+		}
+		return
+	}
+
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+	changeType := ChangeModified
+	if from != nil && to != nil && valueKind(from) != valueKind(to) {
+		changeType = ChangeTypeChanged
+	}
+	*changes = append(*changes, Change{Type: changeType, Path: path, From: from, To: to})
+}
+
+// TargetDiff is the difference between the resolved Data of two targets, see [Inventory.DiffTargets].
+type TargetDiff struct {
+	// From is the resolved Data of the first target.
+	From Data
+	// To is the resolved Data of the second target.
+	To Data
+	// Changes turn From into To, see [Data.Diff].
+	Changes []Change
+}
+
+// DiffTargets returns the differences between the resolved Data of the targets a and b, see [Data.Diff].
+// The Data is resolved like [Inventory.Data] does, without secret handling.
+func (inv *Inventory) DiffTargets(a, b string, predefinedVariables map[string]interface{}, opts ...DataOption) (*TargetDiff, error) {
+	return diffTargets(inv, a, inv, b, predefinedVariables, opts...)
+}
+
+// DiffBase returns the differences between the target of the base inventory (e.g. an older checkout)
+// and the same target of this inventory, see [Inventory.DiffTargets].
+func (inv *Inventory) DiffBase(base *Inventory, target string, predefinedVariables map[string]interface{}, opts ...DataOption) (*TargetDiff, error) {
+	if base == nil {
+		return nil, fmt.Errorf("base inventory cannot be nil")
+	}
+	return diffTargets(base, target, inv, target, predefinedVariables, opts...)
+}
+
+// diffTargets resolves target a of inventory invA and target b of inventory invB and compares them.
+func diffTargets(invA *Inventory, a string, invB *Inventory, b string, predefinedVariables map[string]interface{}, opts ...DataOption) (*TargetDiff, error) {
+	labelA := a
+	if invA != invB {
+		labelA = "base/" + a
+	}
+
+	from, err := invA.Data(a, predefinedVariables, true, false, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target '%s': %w", labelA, err)
+	}
+	to, err := invB.Data(b, predefinedVariables, true, false, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target '%s': %w", b, err)
+	}
+	return &TargetDiff{From: from, To: to, Changes: from.Diff(to)}, nil
+}
+
+// RenderDiffText renders the changes as text, one change per line, see [Change.String].
+func RenderDiffText(changes []Change) string {
+	var b strings.Builder
+	for _, change := range changes {
+		b.WriteString(change.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// RenderDiffJSON renders the changes as indented JSON list.
+func RenderDiffJSON(changes []Change) ([]byte, error) {
+	if changes == nil {
+		changes = []Change{}
+	}
+	return json.MarshalIndent(changes, "", "  ")
+}
+
+// diffContextLines is the number of unchanged lines around every change of a unified diff.
+const diffContextLines = 3
+
+// RenderDiffYAML renders both Data trees as YAML and returns their unified diff.
+// The names are used in the header of the diff. The diff is empty if both trees are equal.
+func RenderDiffYAML(a, b Data, nameA, nameB string) (string, error) {
+	yamlA, err := yaml.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	yamlB, err := yaml.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+
+	return unifiedDiff(splitLines(string(yamlA)), splitLines(string(yamlB)), nameA, nameB), nil
+}
+
+// splitLines splits the text into lines, without a trailing empty line.
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" || text == "{}" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffOp is a single line of a line-based diff.
+type diffOp struct {
+	// kind is ' ' (unchanged), '-' (removed) or '+' (added).
+	kind byte
+	line string
+}
+
+// diffLines returns the shortest edit script which turns a into b, using the linear space variant of the algorithm of Myers.
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	diffRange(a, b, &ops)
+	return ops
+}
+
+// diffRange appends the edit script of a and b to ops.
+// The lines are split at the middle snake of the shortest edit script, both halves are diffed recursively.
+func diffRange(a, b []string, ops *[]diffOp) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		*ops = append(*ops, diffOp{kind: ' ', line: a[0]})
+		a, b = a[1:], b[1:]
+	}
+	common := 0
+	for common < len(a) && common < len(b) && a[len(a)-1-common] == b[len(b)-1-common] {
+		common++
+	}
+	suffix := a[len(a)-common:]
+	a, b = a[:len(a)-common], b[:len(b)-common]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			*ops = append(*ops, diffOp{kind: '+', line: line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			*ops = append(*ops, diffOp{kind: '-', line: line})
+		}
+	default:
+		x, y, u, v := middleSnake(a, b)
+		diffRange(a[:x], b[:y], ops)
+		for _, line := range a[x:u] {
+			*ops = append(*ops, diffOp{kind: ' ', line: line})
+		}
+		diffRange(a[u:], b[v:], ops)
+	}
+
+	for _, line := range suffix {
+		*ops = append(*ops, diffOp{kind: ' ', line: line})
+	}
+}
+
+// middleSnake searches the shortest edit script from both ends at once and returns the snake (a run of equal lines)
+// from (x, y) to (u, v) where both searches meet. The snake is part of a shortest edit script.
+// Only the furthest reaching paths of the current step are kept, hence the memory is linear in the number of lines.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+
+	// forward[k] is the furthest x on diagonal k (x - y = k), backward[k] is the same for the reversed lines
+	forward := make([]int, 2*limit+3)
+	backward := make([]int, 2*limit+3)
+
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+
+			// the backward search has reached diagonal k in step d-1
+			if odd && k >= delta-(d-1) && k <= delta+(d-1) && u+backward[offset+delta-k] >= n {
+				return x, y, u, v
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[n-1-u] == b[m-1-v] {
+				u++
+				v++
+			}
+			backward[offset+k] = u
+
+			// the forward search has reached diagonal delta-k in step d
+			if !odd && delta-k >= -d && delta-k <= d && u+forward[offset+delta-k] >= n {
+				return n - u, m - v, n - x, m - y
+			}
+		}
+	}
+
+	// unreachable, both searches always meet
+	return 0, 0, 0, 0
+}
+
+// unifiedDiff returns the unified diff of the lines, it is empty if both are equal.
+func unifiedDiff(a, b []string, nameA, nameB string) string {
+	ops := diffLines(a, b)
+
+	// every hunk covers the changes which are at most 2*diffContextLines apart, including their context
+	type hunk struct{ start, end int }
+	var hunks []hunk
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		start, end := i-diffContextLines, i+diffContextLines+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1].end {
+			hunks[len(hunks)-1].end = end
+			continue
+		}
+		hunks = append(hunks, hunk{start: start, end: end})
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+
+	// lineA and lineB are the 0-based line numbers of the current op in a and b
+	lineA, lineB, position := 0, 0, 0
+	for _, h := range hunks {
+		for ; position < h.start; position++ {
+			lineA, lineB = advanceLines(ops[position], lineA, lineB)
+		}
+
+		var countA, countB int
+		for _, op := range ops[h.start:h.end] {
+			countA, countB = advanceLines(op, countA, countB)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(lineA, countA), hunkRange(lineB, countB))
+
+		for ; position < h.end; position++ {
+			op := ops[position]
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.line)
+			lineA, lineB = advanceLines(op, lineA, lineB)
+		}
+	}
+	return out.String()
+}
+
+// advanceLines returns the line numbers of a and b after the op.
+func advanceLines(op diffOp, lineA, lineB int) (int, int) {
+	if op.kind != '+' {
+		lineA++
+	}
+	if op.kind != '-' {
+		lineB++
+	}
+	return lineA, lineB
+}
+
+// hunkRange formats the range of a hunk, lines are 1-based and an empty range points to the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
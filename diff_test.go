@@ -0,0 +1,154 @@
+package skipper_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestDataDiff(t *testing.T) {
+	a := skipper.Data{
+		"name":    "dev",
+		"network": map[string]interface{}{"cidr": "10.0.0.0/8", "dns": []interface{}{"1.1.1.1", "8.8.8.8"}},
+		"tags":    map[string]interface{}{"owner": "ops"},
+		"legacy":  true,
+		"same":    []interface{}{1, 2},
+	}
+	b := skipper.Data{
+		"name":     "prd",
+		"network":  map[string]interface{}{"cidr": "10.1.0.0/16", "dns": []interface{}{"1.1.1.1"}},
+		"tags":     "none",
+		"replicas": 3,
+		"same":     []interface{}{1, 2},
+	}
+
+	changes := a.Diff(b)
+	assert.Equal(t, []skipper.Change{
+		{Type: skipper.ChangeRemoved, Path: []interface{}{"legacy"}, From: true},
+		{Type: skipper.ChangeModified, Path: []interface{}{"name"}, From: "dev", To: "prd"},
+		{Type: skipper.ChangeModified, Path: []interface{}{"network", "cidr"}, From: "10.0.0.0/8", To: "10.1.0.0/16"},
+		{Type: skipper.ChangeRemoved, Path: []interface{}{"network", "dns", 1}, From: "8.8.8.8"},
+		{Type: skipper.ChangeAdded, Path: []interface{}{"replicas"}, To: 3},
+		{Type: skipper.ChangeTypeChanged, Path: []interface{}{"tags"}, From: map[string]interface{}{"owner": "ops"}, To: "none"},
+	}, changes)
+	assert.Empty(t, a.Diff(a))
+
+	expected := `- legacy: true
+~ name: "dev" -> "prd"
+~ network.cidr: "10.0.0.0/8" -> "10.1.0.0/16"
+- network.dns[1]: "8.8.8.8"
++ replicas: 3
+! tags: {"owner":"ops"} (map) -> "none" (string)
+`
+	assert.Equal(t, expected, skipper.RenderDiffText(changes))
+
+	out, err := skipper.RenderDiffJSON(changes[:2])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "removed", "path": "legacy", "from": true},
+		{"type": "modified", "path": "name", "from": "dev", "to": "prd"}
+	]`, string(out))
+
+	out, err = skipper.RenderDiffJSON([]skipper.Change{
+		{Type: skipper.ChangeAdded, Path: []interface{}{"empty"}},
+		{Type: skipper.ChangeModified, Path: []interface{}{"cleared"}, From: "value"},
+		{Type: skipper.ChangeRemoved, Path: []interface{}{"null"}},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"type": "added", "path": "empty", "to": null},
+		{"type": "modified", "path": "cleared", "from": "value", "to": null},
+		{"type": "removed", "path": "null", "from": null}
+	]`, string(out))
+
+	out, err = skipper.RenderDiffJSON(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", string(out))
+}
+
+func TestRenderDiffYAML(t *testing.T) {
+	a, b := skipper.Data{}, skipper.Data{}
+	for i := 1; i <= 14; i++ {
+		a[fmt.Sprintf("k%02d", i)] = i
+		b[fmt.Sprintf("k%02d", i)] = i
+	}
+	b["k02"] = 20
+	b["k13"] = 130
+
+	out, err := skipper.RenderDiffYAML(a, b, "dev", "prd")
+	require.NoError(t, err)
+	expected := `--- dev
++++ prd
+@@ -1,5 +1,5 @@
+ k01: 1
+-k02: 2
++k02: 20
+ k03: 3
+ k04: 4
+ k05: 5
+@@ -10,5 +10,5 @@
+ k10: 10
+ k11: 11
+ k12: 12
+-k13: 13
++k13: 130
+ k14: 14
+`
+	assert.Equal(t, expected, out)
+
+	out, err = skipper.RenderDiffYAML(a, a, "dev", "dev")
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	out, err = skipper.RenderDiffYAML(nil, skipper.Data{"a": 1}, "empty", "new")
+	require.NoError(t, err)
+	assert.Equal(t, "--- empty\n+++ new\n@@ -0,0 +1 @@\n+a: 1\n", out)
+}
+
+func TestInventoryDiffTargets(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/app.yaml": "app:\n  replicas: 1\n  name: ${target_name}\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [app]\n",
+		"targets/prd.yaml": "target:\n  skipper:\n    use: [app]\n  app:\n    replicas: 3\n",
+	})
+	require.NoError(t, err)
+
+	diff, err := inventory.DiffTargets("dev", "prd", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []skipper.Change{
+		{Type: skipper.ChangeModified, Path: []interface{}{"app", "name"}, From: "dev", To: "prd"},
+		{Type: skipper.ChangeModified, Path: []interface{}{"app", "replicas"}, From: 1, To: 3},
+	}, diff.Changes)
+	assert.Equal(t, diff.Changes, diff.From.Diff(diff.To))
+
+	_, err = inventory.DiffTargets("dev", "missing", nil)
+	assert.ErrorIs(t, err, skipper.ErrTargetNotFound)
+}
+
+func TestInventoryDiffBase(t *testing.T) {
+	base, err := newTestInventory(t, map[string]string{
+		"classes/app.yaml": "app:\n  replicas: 1\n  tier: web\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [app]\n",
+	})
+	require.NoError(t, err)
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/app.yaml": "app:\n  replicas: 2\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [app]\n",
+	})
+	require.NoError(t, err)
+
+	diff, err := inventory.DiffBase(base, "dev", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []skipper.Change{
+		{Type: skipper.ChangeModified, Path: []interface{}{"app", "replicas"}, From: 1, To: 2},
+		{Type: skipper.ChangeRemoved, Path: []interface{}{"app", "tier"}, From: "web"},
+	}, diff.Changes)
+
+	_, err = inventory.DiffBase(base, "missing", nil)
+	assert.ErrorIs(t, err, skipper.ErrTargetNotFound)
+	assert.Contains(t, err.Error(), "failed to resolve target 'base/missing'")
+}
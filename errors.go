@@ -0,0 +1,330 @@
+package skipper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	hclparser "github.com/hashicorp/hcl/hcl/parser"
+)
+
+// InventoryError is a problem inside an inventory file.
+// If the Position is known, the error message shows the location (`file:line:col`) and a snippet of the file.
+type InventoryError struct {
+	// File is the path of the file which contains the problem.
+	File string
+	// Position of the problem inside the file. The Line is 0 if it is not known, the Column is 0 if only the line is known.
+	Position Position
+	// Err is the actual problem.
+	Err error
+
+	// source is the content of the file, used to render the snippet
+	source []byte
+}
+
+func (e *InventoryError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Location())
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	if snippet := e.Snippet(); snippet != "" {
+		b.WriteString("\n")
+		b.WriteString(snippet)
+	}
+	return b.String()
+}
+
+func (e *InventoryError) Unwrap() error {
+	return e.Err
+}
+
+// Location returns the location of the error as `file:line:col`, `file:line` or `file`, depending on what is known.
+func (e *InventoryError) Location() string {
+	switch {
+	case e.Position.Line > 0 && e.Position.Column > 0:
+		return fmt.Sprintf("%s:%d:%d", e.File, e.Position.Line, e.Position.Column)
+	case e.Position.Line > 0:
+		return fmt.Sprintf("%s:%d", e.File, e.Position.Line)
+	default:
+		return e.File
+	}
+}
+
+// Snippet returns the line of the file which contains the problem, with a marker below the column.
+// It is empty if the position or the content of the file is not known.
+func (e *InventoryError) Snippet() string {
+	if e.Position.Line <= 0 || e.source == nil {
+		return ""
+	}
+	lines := strings.Split(string(e.source), "\n")
+	if e.Position.Line > len(lines) {
+		return ""
+	}
+
+	line := strings.TrimRight(lines[e.Position.Line-1], "\r")
+	number := strconv.Itoa(e.Position.Line)
+	snippet := fmt.Sprintf("  %s | %s", number, line)
+	if e.Position.Column > 0 {
+		snippet += fmt.Sprintf("\n  %s | %s^", strings.Repeat(" ", len(number)), strings.Repeat(" ", e.Position.Column-1))
+	}
+	return snippet
+}
+
+// newFileError creates an InventoryError which points to the value at the given path inside the file.
+// If the position of the path is not known, the position of the closest parent is used.
+func newFileError(file *YamlFile, path []interface{}, err error) *InventoryError {
+	inventoryErr := &InventoryError{File: file.Path, Err: err, source: file.Bytes}
+	for i := len(path); i > 0; i-- {
+		if position, ok := file.Position(path[:i]...); ok {
+			inventoryErr.Position = position
+			break
+		}
+	}
+	return inventoryErr
+}
+
+// yamlErrorLineRegex extracts the line of yaml errors like `yaml: line 3: mapping values are not allowed in this context`.
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// newDecodeError creates an InventoryError for a file which cannot be decoded.
+// The position is extracted from the errors of the supported formats, if possible.
+func newDecodeError(path string, source []byte, err error) *InventoryError {
+	inventoryErr := &InventoryError{File: path, Err: err, source: source}
+
+	var (
+		jsonSyntaxErr *json.SyntaxError
+		jsonTypeErr   *json.UnmarshalTypeError
+		tomlErr       toml.ParseError
+		hclErr        *hclparser.PosError
+	)
+	switch {
+	case errors.As(err, &jsonSyntaxErr):
+		inventoryErr.Position = offsetPosition(source, jsonSyntaxErr.Offset)
+	case errors.As(err, &jsonTypeErr):
+		inventoryErr.Position = offsetPosition(source, jsonTypeErr.Offset)
+	case errors.As(err, &tomlErr):
+		inventoryErr.Position = offsetPosition(source, int64(tomlErr.Position.Start))
+		if inventoryErr.Position.Line != tomlErr.Position.Line {
+			inventoryErr.Position = Position{Line: tomlErr.Position.Line}
+		}
+	case errors.As(err, &hclErr):
+		inventoryErr.Position = Position{Line: hclErr.Pos.Line, Column: hclErr.Pos.Column}
+	default:
+		if match := yamlErrorLineRegex.FindStringSubmatch(err.Error()); match != nil {
+			inventoryErr.Position.Line, _ = strconv.Atoi(match[1])
+		}
+	}
+
+	return inventoryErr
+}
+
+// offsetPosition converts the byte offset inside source into a position.
+func offsetPosition(source []byte, offset int64) Position {
+	if offset < 0 || offset > int64(len(source)) {
+		return Position{}
+	}
+	before := source[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return Position{Line: line, Column: column}
+}
+
+// ErrorList is a list of errors, it is used to report all problems at once instead of stopping at the first one.
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, err := range l {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred:\n%s", len(l), strings.Join(messages, "\n"))
+}
+
+// Is reports whether any error in the list matches target, see [errors.Is].
+func (l ErrorList) Is(target error) bool {
+	for _, err := range l {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error in the list which matches target, see [errors.As].
+func (l ErrorList) As(target interface{}) bool {
+	for _, err := range l {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends the error to the list, nil errors and duplicates are ignored.
+// The errors of another ErrorList are added individually.
+func (l *ErrorList) add(err error) {
+	if err == nil {
+		return
+	}
+	if list, ok := err.(ErrorList); ok {
+		for _, e := range list {
+			l.add(e)
+		}
+		return
+	}
+	for _, existing := range *l {
+		if existing.Error() == err.Error() {
+			return
+		}
+	}
+	*l = append(*l, err)
+}
+
+// addWrapped adds every error contained in err with the given prefix.
+func (l *ErrorList) addWrapped(prefix string, err error) {
+	if list, ok := err.(ErrorList); ok {
+		for _, e := range list {
+			l.addWrapped(prefix, e)
+		}
+		return
+	}
+	if err != nil {
+		l.add(fmt.Errorf("%s: %w", prefix, err))
+	}
+}
+
+// err returns nil if the list is empty, the only error if it contains one error and the list otherwise.
+func (l ErrorList) err() error {
+	switch len(l) {
+	case 0:
+		return nil
+	case 1:
+		return l[0]
+	default:
+		return l
+	}
+}
+
+var (
+	// ErrTargetNotFound is returned if a target does not exist in the inventory.
+	ErrTargetNotFound = fmt.Errorf("target could not be loaded")
+	// ErrClassNotFound is returned if a target or class uses a class which does not exist in the inventory.
+	ErrClassNotFound = fmt.Errorf("class does not exist")
+)
+
+// sentinelError is a descriptive error which matches a sentinel error (e.g. [ErrClassNotFound]) with [errors.Is].
+type sentinelError struct {
+	sentinel error
+	message  string
+}
+
+func newSentinelError(sentinel error, format string, args ...interface{}) error {
+	return &sentinelError{sentinel: sentinel, message: fmt.Sprintf(format, args...)}
+}
+
+func (e *sentinelError) Error() string {
+	return e.message
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+// KeyNotFoundError is returned if a path points to a key which does not exist in the [Data].
+type KeyNotFoundError struct {
+	// Key which does not exist.
+	Key interface{}
+	// Path to the key, including the key itself.
+	Path []interface{}
+}
+
+func (e *KeyNotFoundError) Error() string {
+	return fmt.Sprintf("key not found: %v", e.Key)
+}
+
+// UndefinedVariableError is returned if a variable (`${...}`) references a value which cannot be resolved.
+type UndefinedVariableError struct {
+	// Variable is the full name of the variable, e.g. `${foo:bar}`.
+	Variable string
+	// Path of the value inside the Data which uses the variable.
+	Path []interface{}
+	// Err is the reason why the variable cannot be resolved.
+	Err error
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("reference to invalid variable '%s': %v", e.Variable, e.Err)
+}
+
+func (e *UndefinedVariableError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UndefinedVariableError) dataPath() []interface{} {
+	return e.Path
+}
+
+// InvalidCallError is returned if a call (`%{...}`) uses a function which does not exist.
+type InvalidCallError struct {
+	// Function is the name of the function.
+	Function string
+	// Path of the value inside the Data which uses the call. It is nil if the call is not used inside Data.
+	Path []interface{}
+}
+
+func (e *InvalidCallError) Error() string {
+	return fmt.Sprintf("invalid call function '%s'", e.Function)
+}
+
+func (e *InvalidCallError) dataPath() []interface{} {
+	return e.Path
+}
+
+// SecretNotFoundError is returned if the file of a secret (`?{...}`) does not exist and it cannot be created.
+type SecretNotFoundError struct {
+	// Secret is the full name of the secret, e.g. `?{aes:foo/bar}`.
+	Secret string
+	// File is the path of the secret file.
+	File string
+	// Path of the value inside the Data which uses the secret.
+	Path []interface{}
+}
+
+func (e *SecretNotFoundError) Error() string {
+	return fmt.Sprintf("secret '%s' does not exist: %s", e.Secret, e.File)
+}
+
+func (e *SecretNotFoundError) dataPath() []interface{} {
+	return e.Path
+}
+
+// dataPathError is an error which is caused by the value at a path inside the Data of a target.
+// It allows to point the error to the file which defines the value, see [Inventory.locateError].
+type dataPathError interface {
+	error
+	dataPath() []interface{}
+}
+
+// findListItem returns the path of the item of the list at listPath inside the file which equals value.
+// If the list does not contain the value, listPath is returned.
+func findListItem(file *YamlFile, listPath []interface{}, value string) []interface{} {
+	list, err := file.Data.GetPath(listPath...)
+	if err != nil {
+		return listPath
+	}
+	items, ok := list.([]interface{})
+	if !ok {
+		return listPath
+	}
+	for i, item := range items {
+		if fmt.Sprint(item) == value {
+			return appendPath(listPath, i)
+		}
+	}
+	return listPath
+}
@@ -0,0 +1,202 @@
+package skipper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestInventoryErrorPositions(t *testing.T) {
+	table := []struct {
+		TestName         string
+		Files            map[string]string
+		ExpectedFile     string
+		ExpectedPosition skipper.Position
+		ExpectedError    string
+	}{
+		{
+			TestName: "InvalidYaml",
+			Files: map[string]string{
+				"classes/a.yaml": "a:\n  foo: [\n",
+			},
+			ExpectedFile:     "/inventory/classes/a.yaml",
+			ExpectedPosition: skipper.Position{Line: 2},
+			ExpectedError:    "failed to decode",
+		},
+		{
+			TestName: "InvalidJson",
+			Files: map[string]string{
+				"classes/a.json": "{\"a\": {\"x\": 1,}}",
+			},
+			ExpectedFile:     "/inventory/classes/a.json",
+			ExpectedPosition: skipper.Position{Line: 1, Column: 16},
+			ExpectedError:    "invalid character",
+		},
+		{
+			TestName: "RootKeyMismatch",
+			Files: map[string]string{
+				"classes/a.yaml": "# comment\nb:\n  foo: bar\n",
+			},
+			ExpectedFile:     "/inventory/classes/a.yaml",
+			ExpectedPosition: skipper.Position{Line: 2, Column: 1},
+			ExpectedError:    "the root key in the 'b' class differs from the filename: a",
+		},
+		{
+			TestName: "MissingClass",
+			Files: map[string]string{
+				"classes/a.yaml":   "a:\n  skipper:\n    use:\n      - b\n      - missing\n",
+				"classes/b.yaml":   "b:\n  foo: bar\n",
+				"targets/dev.yaml": "target:\n  skipper:\n    use: [b]\n",
+			},
+			ExpectedFile:     "/inventory/classes/a.yaml",
+			ExpectedPosition: skipper.Position{Line: 5, Column: 9},
+			ExpectedError:    "class 'a' uses class which does not exist: missing",
+		},
+		{
+			TestName: "MissingParentTarget",
+			Files: map[string]string{
+				"classes/a.yaml":   "a:\n  foo: bar\n",
+				"targets/dev.yaml": "target:\n  skipper:\n    extends: missing\n",
+			},
+			ExpectedFile:     "/inventory/targets/dev.yaml",
+			ExpectedPosition: skipper.Position{Line: 3, Column: 5},
+			ExpectedError:    "target 'dev' extends target which does not exist: missing",
+		},
+		{
+			TestName: "ClassUsedByDefaults",
+			Files: map[string]string{
+				"classes/a.yaml":         "a:\n  foo: bar\n",
+				"targets/_defaults.yaml": "target:\n  skipper:\n    use: [missing]\n",
+				"targets/dev.yaml":       "target:\n  skipper: {}\n",
+			},
+			ExpectedFile:     "/inventory/targets/_defaults.yaml",
+			ExpectedPosition: skipper.Position{Line: 3, Column: 11},
+			ExpectedError:    "target 'dev' uses class which does not exist: missing",
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.TestName, func(t *testing.T) {
+			_, err := newTestInventory(t, tt.Files)
+			require.Error(t, err)
+
+			var inventoryErr *skipper.InventoryError
+			require.True(t, errors.As(err, &inventoryErr), err.Error())
+			assert.Equal(t, tt.ExpectedFile, inventoryErr.File)
+			assert.Equal(t, tt.ExpectedPosition, inventoryErr.Position)
+			assert.Contains(t, inventoryErr.Err.Error(), tt.ExpectedError)
+		})
+	}
+}
+
+func TestInventoryErrorSnippet(t *testing.T) {
+	_, err := newTestInventory(t, map[string]string{
+		"classes/a.yaml":   "a:\n  skipper:\n    use: [missing]\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [a]\n",
+	})
+	require.Error(t, err)
+
+	expected := "/inventory/classes/a.yaml:3:11: class 'a' uses class which does not exist: missing\n" +
+		"  3 |     use: [missing]\n" +
+		"    |           ^"
+	assert.Equal(t, expected, err.Error())
+}
+
+func TestInventoryCollectsErrors(t *testing.T) {
+	_, err := newTestInventory(t, map[string]string{
+		"classes/a.yaml":   "b:\n  foo: bar\n",
+		"classes/c.yaml":   "c:\n  foo: [\n",
+		"classes/d.yaml":   "d:\n  foo: bar\n",
+		"targets/dev.yaml": "target:\n  foo: bar\n",
+	})
+	require.Error(t, err)
+
+	var errs skipper.ErrorList
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), "/inventory/classes/a.yaml:1:1")
+	assert.Contains(t, errs[1].Error(), "/inventory/classes/c.yaml:2")
+	assert.Contains(t, errs[2].Error(), "/inventory/targets/dev.yaml:1:1: missing skipper key in target")
+	assert.Contains(t, err.Error(), "3 errors occurred")
+
+	// problems of classes are reported once, not for every target which uses them
+	_, err = newTestInventory(t, map[string]string{
+		"classes/a.yaml":   "a:\n  skipper:\n    use: [b]\n",
+		"classes/b.yaml":   "b:\n  skipper:\n    use: [a]\n",
+		"classes/c.yaml":   "c:\n  skipper:\n    use: [missing]\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [a, c]\n",
+		"targets/prd.yaml": "target:\n  skipper:\n    use: [a, c]\n",
+	})
+	require.Error(t, err)
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	assert.Contains(t, errs[0].Error(), "class import cycle detected: a -> b -> a")
+	assert.Contains(t, errs[1].Error(), "class 'c' uses class which does not exist: missing")
+}
+
+func TestInventoryDataErrorPosition(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/a.yaml":   "a:\n  name: foo\n  ref: ${a:name:sub}\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [a]\n",
+	})
+	require.NoError(t, err)
+
+	_, err = inventory.Data("dev", nil, true, false)
+	require.Error(t, err)
+
+	var inventoryErr *skipper.InventoryError
+	require.True(t, errors.As(err, &inventoryErr))
+	assert.Equal(t, "/inventory/classes/a.yaml", inventoryErr.File)
+	assert.Equal(t, skipper.Position{Line: 3, Column: 3}, inventoryErr.Position)
+	assert.Contains(t, err.Error(), "reference to invalid variable '${a:name:sub}'")
+}
+
+func TestTypedErrors(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/a.yaml":      "a:\n  name: foo\n",
+		"targets/dev.yaml":    "target:\n  skipper:\n    use: [a]\n",
+		"targets/var.yaml":    "target:\n  skipper:\n    use: [a]\n  ref: ${a:name:sub}\n",
+		"targets/call.yaml":   "target:\n  skipper:\n    use: [a]\n  value: '%{nope:param}'\n",
+		"targets/secret.yaml": "target:\n  skipper:\n    use: [a]\n  password: ?{plain:missing.json}\n",
+	})
+	require.NoError(t, err)
+
+	_, err = inventory.Data("missing", nil, true, false)
+	assert.ErrorIs(t, err, skipper.ErrTargetNotFound)
+
+	_, err = newTestInventory(t, map[string]string{
+		"classes/a.yaml":   "a:\n  name: foo\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [a, missing]\n",
+	})
+	assert.ErrorIs(t, err, skipper.ErrClassNotFound)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	_, err = data.GetPath("a", "missing")
+	var keyErr *skipper.KeyNotFoundError
+	require.True(t, errors.As(err, &keyErr))
+	assert.Equal(t, "missing", keyErr.Key)
+	assert.Equal(t, []interface{}{"a", "missing"}, keyErr.Path)
+
+	_, err = inventory.Data("var", nil, true, false)
+	var variableErr *skipper.UndefinedVariableError
+	require.True(t, errors.As(err, &variableErr))
+	assert.Equal(t, "${a:name:sub}", variableErr.Variable)
+	assert.Equal(t, []interface{}{"ref"}, variableErr.Path)
+
+	_, err = inventory.Data("call", nil, true, false)
+	var callErr *skipper.InvalidCallError
+	require.True(t, errors.As(err, &callErr))
+	assert.Equal(t, "nope", callErr.Function)
+	assert.Equal(t, []interface{}{"value"}, callErr.Path)
+
+	_, err = inventory.Data("secret", nil, false, false)
+	var secretErr *skipper.SecretNotFoundError
+	require.True(t, errors.As(err, &secretErr))
+	assert.Equal(t, "?{plain:missing.json}", secretErr.Secret)
+	assert.Equal(t, []interface{}{"password"}, secretErr.Path)
+}
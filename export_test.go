@@ -0,0 +1,23 @@
+package skipper
+
+import "strings"
+
+// RestoreDecoder registers the decoder which is currently registered for the extension again once the test is done,
+// or removes the extension if no decoder was registered. Tests use it to keep the global registry unchanged.
+func RestoreDecoder(t interface{ Cleanup(func()) }, extension string) {
+	extension = strings.ToLower(extension)
+
+	decodersMu.RLock()
+	previous, existed := decoders[extension]
+	decodersMu.RUnlock()
+
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		if existed {
+			decoders[extension] = previous
+			return
+		}
+		delete(decoders, extension)
+	})
+}
@@ -11,8 +11,7 @@ import (
 )
 
 var (
-	yamlFileExtensions = []string{".yml", ".yaml", ""}
-	ErrFilePathEmpty   = fmt.Errorf("file path is empty")
+	ErrFilePathEmpty = fmt.Errorf("file path is empty")
 )
 
 // File is just an arbitrary description of a path and the data of the File to which Path points to.
@@ -64,16 +63,20 @@ type YamlFileLoaderFunc func(file *YamlFile, relativePath string) error
 // A path relative to the given pasePath is constructed.
 // The loaded yaml file and the relative path are then passed to the given YamlFileLoaderFunc
 // which is responsible for creating specific types from the YamlFile.
+//
+// All files are loaded, even if some of them fail. If more than one file fails, an [ErrorList] is returned.
 func YamlFileLoader(fileSystem afero.Fs, basePath string, loader YamlFileLoaderFunc) error {
 	yamlFiles, err := DiscoverYamlFiles(fileSystem, basePath)
 	if err != nil {
 		return err
 	}
 
+	var errs ErrorList
 	for _, yamlFile := range yamlFiles {
 		err = yamlFile.Load(fileSystem)
 		if err != nil {
-			return err
+			errs.add(err)
+			continue
 		}
 
 		// skip empty files
@@ -84,19 +87,19 @@ func YamlFileLoader(fileSystem afero.Fs, basePath string, loader YamlFileLoaderF
 		relativePath := strings.ReplaceAll(yamlFile.Path, basePath, "")
 		relativePath = strings.TrimLeft(relativePath, "/")
 
-		err = loader(yamlFile, relativePath)
-		if err != nil {
-			return err
-		}
+		errs.add(loader(yamlFile, relativePath))
 	}
 
-	return nil
+	return errs.err()
 }
 
 // YamlFile is what is used for all inventory-relevant files (classes, secrets and targets).
 type YamlFile struct {
 	File
 	Data Data
+
+	// positions of all values, only available for yaml files
+	positions map[string]Position
 }
 
 // NewYamlFile returns a newly initialized `YamlFile`.
@@ -128,7 +131,8 @@ func CreateNewYamlFile(fs afero.Fs, path string, data []byte) (*YamlFile, error)
 	return NewYamlFile(path)
 }
 
-// Load will first load the underlying raw file-data and then attempt to `yaml.Unmarshal` it into `Data`
+// Load will first load the underlying raw file-data and then decode it into `Data`, using the [Decoder]
+// which is registered for the file extension. Besides yaml, this can be json, toml or hcl for example.
 // The resulting Data is stored in `YamlFile.Data`.
 func (f *YamlFile) Load(fs afero.Fs) error {
 	err := f.File.Load(fs)
@@ -136,11 +140,12 @@ func (f *YamlFile) Load(fs afero.Fs) error {
 		return err
 	}
 
-	var d Data
-	if err := yaml.Unmarshal(f.Bytes, &d); err != nil {
-		return err
+	d, err := decodeFile(f.Path, f.Bytes)
+	if err != nil {
+		return newDecodeError(f.Path, f.Bytes, fmt.Errorf("failed to decode: %w", err))
 	}
 	f.Data = d
+	f.loadPositions()
 	return nil
 }
 
@@ -175,14 +180,30 @@ func (sf *SecretFile) LoadSecretFileData(fs afero.Fs) error {
 		return err
 	}
 
+	data, err := decodeFile(sf.Path, sf.Bytes)
+	if err != nil {
+		return newDecodeError(sf.Path, sf.Bytes, fmt.Errorf("failed to decode: %w", err))
+	}
+	sf.YamlFile.Data = data
+
 	var d SecretFileData
-	if err := yaml.Unmarshal(sf.Bytes, &d); err != nil {
+	if err := sf.UnmarshalPath(&d); err != nil {
 		return err
 	}
 	sf.Data = d
 	return nil
 }
 
+// copy returns a copy of the secret file, which can be loaded without affecting the original.
+func (sf *SecretFile) copy() *SecretFile {
+	out := *sf
+	if sf.YamlFile != nil {
+		yamlFile := *sf.YamlFile
+		out.YamlFile = &yamlFile
+	}
+	return &out
+}
+
 type SecretFileList []*SecretFile
 
 func NewSecretFile(file *YamlFile, relativeSecretPath string) (*SecretFile, error) {
@@ -11,6 +11,7 @@ import (
 
 // DiscoverYamlFiles iterates over a given rootPath recursively, filters out all files with the appropriate file fileExtensions
 // and finally creates a YamlFile slice which is then returned.
+// The file extensions are the ones for which a [Decoder] is registered, so the files are not necessarily yaml.
 func DiscoverYamlFiles(fileSystem afero.Fs, rootPath string) ([]*YamlFile, error) {
 	exists, err := afero.Exists(fileSystem, rootPath)
 	if err != nil {
@@ -20,14 +21,10 @@ func DiscoverYamlFiles(fileSystem afero.Fs, rootPath string) ([]*YamlFile, error
 		return nil, fmt.Errorf("file path does not exist: %s", rootPath)
 	}
 
+	// every file for which a decoder is registered is an inventory file
 	matchesExtension := func(path string) bool {
-		ext := filepath.Ext(path)
-		for _, extension := range yamlFileExtensions {
-			if extension == ext {
-				return true
-			}
-		}
-		return false
+		_, ok := DecoderFor(path)
+		return ok
 	}
 
 	var files []*YamlFile
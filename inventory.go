@@ -1,9 +1,13 @@
 package skipper
 
 import (
+	"errors"
 	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/lukasjarosch/skipper/secret"
 	"github.com/spf13/afero"
@@ -20,11 +24,47 @@ type Inventory struct {
 	secretFiles []*SecretFile
 	classFiles  []*Class
 	targetFiles []*Target
+	// targetDefaults are the directory defaults (`_defaults.yaml`) found inside the targetPath
+	targetDefaults []*Target
+	// secretMu guards the creation of secret files
+	secretMu sync.Mutex
+	// classRoots are additional class roots which are loaded before the classPath
+	classRoots []ClassRoot
+	// schemaPath is the directory which contains the schema files of classes, see [WithSchemaPath]
+	schemaPath string
+	// classSchemas are the JSON schemas of every class, indexed by the class name
+	classSchemas map[string][]*schema
+	// targetSchemas are the inline JSON schemas of targets and directory defaults
+	targetSchemas map[*Target]*schema
+}
+
+// ClassRoot is a directory which contains classes, for example a class library which is shared by multiple projects.
+type ClassRoot struct {
+	// Name identifies the root, it defaults to the Path.
+	Name string
+	// Fs is the filesystem of the root. If nil, the filesystem of the inventory is used.
+	Fs afero.Fs
+	// Path of the root directory inside Fs.
+	Path string
+}
+
+// InventoryOption configures optional behaviour of [NewInventory].
+type InventoryOption func(*Inventory)
+
+// WithClassRoots adds class roots which are loaded before the classPath of the inventory.
+//
+// If multiple roots define a class with the same name, the class of the later root shadows the earlier ones entirely,
+// the classes are not merged. The classPath of the inventory is always the last root, hence project classes shadow all others.
+// Every class knows from which root it was loaded ([Class.Root]) and which classes it shadows ([Class.Shadowed]).
+func WithClassRoots(roots ...ClassRoot) InventoryOption {
+	return func(inv *Inventory) {
+		inv.classRoots = append(inv.classRoots, roots...)
+	}
 }
 
 // NewInventory creates a new Inventory with the given afero.Fs.
 // At least one extension must be provided, otherwise an error is returned.
-func NewInventory(fs afero.Fs, classPath, targetPath, secretPath string) (*Inventory, error) {
+func NewInventory(fs afero.Fs, classPath, targetPath, secretPath string, opts ...InventoryOption) (*Inventory, error) {
 	if fs == nil {
 		return nil, fmt.Errorf("fs cannot be nil")
 	}
@@ -54,6 +94,14 @@ func NewInventory(fs afero.Fs, classPath, targetPath, secretPath string) (*Inven
 		targetPath: targetPath,
 		secretPath: secretPath,
 	}
+	for _, opt := range opts {
+		opt(inv)
+	}
+	for i, root := range inv.classRoots {
+		if root.Path == "" {
+			return nil, fmt.Errorf("class root %d: path cannot be empty", i)
+		}
+	}
 
 	err := inv.load()
 	if err != nil {
@@ -65,37 +113,161 @@ func NewInventory(fs afero.Fs, classPath, targetPath, secretPath string) (*Inven
 
 // load will discover and load all classes and targets given the paths.
 // It will also ensure that all targets only use classes which are actually defined.
+//
+// Problems are collected instead of stopping at the first one: first all files are loaded,
+// then, if every file could be loaded, all classes and targets are resolved.
+// If more than one problem occurs, an [ErrorList] is returned.
 func (inv *Inventory) load() error {
-	err := YamlFileLoader(inv.fs, inv.classPath, classYamlFileLoader(&inv.classFiles))
-	if err != nil {
-		return fmt.Errorf("unable to load class files: %w", err)
+	var errs ErrorList
+	errs.add(inv.loadClasses())
+	errs.addWrapped("unable to load target files", YamlFileLoader(inv.fs, inv.targetPath, targetYamlFileLoader(&inv.targetFiles, &inv.targetDefaults)))
+	errs.addWrapped("unable to load secret files", YamlFileLoader(inv.fs, inv.secretPath, secretYamlFileLoader(&inv.secretFiles)))
+	if len(errs) > 0 {
+		return errs.err()
 	}
-	err = YamlFileLoader(inv.fs, inv.targetPath, targetYamlFileLoader(&inv.targetFiles))
-	if err != nil {
-		return fmt.Errorf("unable to load target files: %w", err)
+
+	// resolve the class patterns of all targets and classes into actual class names
+	var classNames []string
+	for _, class := range inv.classFiles {
+		classNames = append(classNames, class.Name)
 	}
-	err = YamlFileLoader(inv.fs, inv.secretPath, secretYamlFileLoader(&inv.secretFiles))
-	if err != nil {
-		return fmt.Errorf("unable to load secret files: %w", err)
+	for _, target := range inv.targetFiles {
+		classes, err := resolveClassUses(target.SkipperConfig.Classes, classNames)
+		if err != nil {
+			errs.add(newFileError(target.File, target.configPath(useKey), fmt.Errorf("target '%s': %w", target.Name, err)))
+			continue
+		}
+		target.SkipperConfig.Classes = classes
+	}
+	for _, defaults := range inv.targetDefaults {
+		if defaults.SkipperConfig == nil {
+			continue
+		}
+		classes, err := resolveClassUses(defaults.SkipperConfig.Classes, classNames)
+		if err != nil {
+			errs.add(newFileError(defaults.File, defaults.configPath(useKey), fmt.Errorf("defaults '%s': %w", defaults.Name, err)))
+			continue
+		}
+		defaults.SkipperConfig.Classes = classes
+	}
+	for _, class := range inv.classFiles {
+		if class.Configuration == nil {
+			continue
+		}
+		classes, err := resolveClassUses(class.Configuration.Classes, classNames)
+		if err != nil {
+			errs.add(newFileError(class.File, class.configPath(useKey), fmt.Errorf("class '%s': %w", class.Name, err)))
+			continue
+		}
+		class.Configuration.Classes = classes
 	}
 
-	// check for all targets whether they use classes which actually exist
+	// collect the directory defaults of every target, the top-most directory first
+	sort.SliceStable(inv.targetDefaults, func(i, j int) bool {
+		return inv.targetDefaults[i].directoryDepth() < inv.targetDefaults[j].directoryDepth()
+	})
 	for _, target := range inv.targetFiles {
-		// check the used wildcard classes by the target
-		// and add them to the "UsedClasses" field if they exist
-		for _, use := range target.UsedWildcardClasses {
-			for _, class := range inv.classFiles {
-				usePrefix := strings.TrimRight(use, "*")
-
-				if strings.HasPrefix(class.Name, usePrefix) {
-					target.SkipperConfig.Classes = append(target.SkipperConfig.Classes, class.Name)
-				}
+		for _, defaults := range inv.targetDefaults {
+			if defaults.appliesTo(target) {
+				target.Defaults = append(target.Defaults, defaults)
+			}
+		}
+	}
 
+	// load the patch files of targets and directory defaults
+	for _, target := range inv.targetFiles {
+		errs.add(inv.loadPatches(target))
+	}
+	for _, defaults := range inv.targetDefaults {
+		errs.add(inv.loadPatches(defaults))
+	}
+
+	// resolve the targets which extend other targets and apply the directory defaults
+	resolvedTargets := make(map[string]bool)
+	for _, target := range inv.targetFiles {
+		errs.add(inv.resolveTargetExtends(target, nil, resolvedTargets))
+	}
+
+	// ensure that all used classes exist and do not form import cycles.
+	// Classes are checked on their own, hence a problem of a class is reported once and not for every target using it.
+	resolvedClasses := make(map[string]bool)
+	for _, class := range inv.classFiles {
+		errs.add(inv.resolveUsedClass(class.Name, nil, resolvedClasses, new([]*Class)))
+	}
+	for _, target := range inv.targetFiles {
+		for _, className := range target.SkipperConfig.Classes {
+			if inv.GetClass(className) == nil {
+				errs.add(target.lineageError(useKey, className, newSentinelError(ErrClassNotFound, "target '%s' uses class which does not exist: %s", target.Name, className)))
 			}
 		}
 	}
+	if len(errs) > 0 {
+		return errs.err()
+	}
 
-	return nil
+	// validate the merged data of every target against the JSON schemas, values which are not resolved yet are skipped
+	errs.add(inv.loadSchemas())
+	if len(errs) > 0 || !inv.hasSchemas() {
+		return errs.err()
+	}
+	for _, target := range inv.targetFiles {
+		data, _, err := inv.mergedData(target, new(dataOptions), nil)
+		if err != nil {
+			// the problem is reported by Data
+			continue
+		}
+		errs.add(inv.validateSchemas(target, data))
+	}
+
+	return errs.err()
+}
+
+// loadClasses loads the classes of all class roots, the classPath of the inventory is loaded last.
+// Classes of later roots shadow classes with the same name of earlier roots.
+// All roots are loaded, even if some of them contain invalid classes.
+func (inv *Inventory) loadClasses() error {
+	roots := append(append([]ClassRoot{}, inv.classRoots...), ClassRoot{Path: inv.classPath})
+
+	var errs ErrorList
+	for _, root := range roots {
+		if root.Name == "" {
+			root.Name = root.Path
+		}
+		fs := root.Fs
+		if fs == nil {
+			fs = inv.fs
+		}
+
+		var classes []*Class
+		err := YamlFileLoader(fs, root.Path, classYamlFileLoader(&classes))
+		if err != nil {
+			if len(roots) > 1 {
+				errs.addWrapped(fmt.Sprintf("unable to load class files of root '%s'", root.Name), err)
+			} else {
+				errs.addWrapped("unable to load class files", err)
+			}
+		}
+
+		for _, class := range classes {
+			class.Root = root.Name
+			inv.addClass(class)
+		}
+	}
+
+	return errs.err()
+}
+
+// addClass adds the class to the inventory. An existing class with the same name is shadowed.
+func (inv *Inventory) addClass(class *Class) {
+	for i, existing := range inv.classFiles {
+		if existing.Name != class.Name {
+			continue
+		}
+		class.Shadowed = append(append([]*Class{}, existing.Shadowed...), existing)
+		inv.classFiles[i] = class
+		return
+	}
+	inv.classFiles = append(inv.classFiles, class)
 }
 
 // GetSkipperConfig merges SkipperConfig of the target and it's used classes into one effective configuration.
@@ -105,16 +277,16 @@ func (inv *Inventory) GetSkipperConfig(targetName string) (config *SkipperConfig
 	// load SkipperConfig of target
 	target := inv.GetTarget(targetName)
 	if target == nil {
-		return nil, fmt.Errorf("target could not be loaded: %s", targetName)
+		return nil, fmt.Errorf("%w: %s", ErrTargetNotFound, targetName)
 	}
 	configurations = append(configurations, target.SkipperConfig)
 
 	// load all GetSkipperConfigs from used classes
-	for _, className := range target.SkipperConfig.Classes {
-		class := inv.GetClass(className)
-		if class == nil {
-			return nil, fmt.Errorf("target '%s' uses class which does not exist: %s", targetName, className)
-		}
+	classes, err := inv.GetUsedClasses(targetName)
+	if err != nil {
+		return nil, err
+	}
+	for _, class := range classes {
 		configurations = append(configurations, class.Configuration)
 	}
 
@@ -122,108 +294,253 @@ func (inv *Inventory) GetSkipperConfig(targetName string) (config *SkipperConfig
 }
 
 // GetUsedClasses returns the loaded classes which are used by the given target.
+//
+// Classes can `use` other classes as well, these are resolved recursively.
+// A class is always returned after all the classes it uses, and the classes are otherwise ordered as they are listed in `use`.
+// Every class is only returned once, even if it is used multiple times.
+// Import cycles between classes result in an error which shows the chain of classes (e.g. `a -> b -> c -> a`).
 func (inv *Inventory) GetUsedClasses(targetName string) ([]*Class, error) {
 	target := inv.GetTarget(targetName)
 	if target == nil {
-		return nil, fmt.Errorf("target could not be loaded: %s", targetName)
+		return nil, fmt.Errorf("%w: %s", ErrTargetNotFound, targetName)
 	}
 
 	var classes []*Class
+	resolved := make(map[string]bool)
 	for _, className := range target.SkipperConfig.Classes {
-		class := inv.GetClass(className)
-		if class == nil {
-			return nil, fmt.Errorf("target '%s' uses class which does not exist: %s", targetName, className)
+		if inv.GetClass(className) == nil {
+			return nil, target.lineageError(useKey, className, newSentinelError(ErrClassNotFound, "target '%s' uses class which does not exist: %s", targetName, className))
+		}
+
+		err := inv.resolveUsedClass(className, nil, resolved, &classes)
+		if err != nil {
+			return nil, fmt.Errorf("target '%s': %w", targetName, err)
 		}
-		classes = append(classes, class)
 	}
 
 	return classes, nil
 }
 
-// Data loads the required inventory data map given the target.
-// This is where variables and secrets are handled and eventually replaced.
-// The resulting Data is what can be passed to the templates.
-func (inv *Inventory) Data(targetName string, predefinedVariables map[string]interface{}, skipSecretHandling, revealSecrets bool) (data Data, err error) {
-	data = make(Data)
+// resolveUsedClass appends the class with the given name to classes, after recursively appending every class it uses.
+// The chain is the list of classes which lead to the given class and is used to detect import cycles.
+// Classes which are already resolved are skipped.
+// Errors point to the `use` entry of the class which uses the problematic class.
+func (inv *Inventory) resolveUsedClass(className string, chain []string, resolved map[string]bool, classes *[]*Class) error {
+	for i, name := range chain {
+		if name == className {
+			cycle := append(append([]string{}, chain[i:]...), className)
+			return inv.classUseError(chain[len(chain)-1], className, fmt.Errorf("class import cycle detected: %s", strings.Join(cycle, " -> ")))
+		}
+	}
 
-	target := inv.GetTarget(targetName)
-	if target == nil {
-		return nil, fmt.Errorf("target could not be loaded: %s", targetName)
+	if resolved[className] {
+		return nil
 	}
 
-	// load all classes as defined by the target
-	classes, err := inv.GetUsedClasses(targetName)
+	class := inv.GetClass(className)
+	if class == nil {
+		return inv.classUseError(chain[len(chain)-1], className, newSentinelError(ErrClassNotFound, "class '%s' uses class which does not exist: %s", chain[len(chain)-1], className))
+	}
+
+	if class.Configuration != nil {
+		chain = append(chain, className)
+		for _, usedClassName := range class.Configuration.Classes {
+			err := inv.resolveUsedClass(usedClassName, chain, resolved, classes)
+			if err != nil {
+				// a class which failed is not resolved again, hence every problem is only reported once
+				resolved[className] = true
+				return err
+			}
+		}
+	}
+
+	resolved[className] = true
+	(*classes) = append((*classes), class)
+
+	return nil
+}
+
+// classUseError creates an error which points to the `use` entry of the class which uses the usedClassName.
+func (inv *Inventory) classUseError(className, usedClassName string, err error) error {
+	class := inv.GetClass(className)
+	if class == nil {
+		return err
+	}
+	return newFileError(class.File, findListItem(class.File, class.configPath(useKey), usedClassName), err)
+}
+
+// resolveTargetExtends lets the target inherit from the target it extends, after the extended target has been resolved itself.
+// The directory defaults of the target are applied before, hence the precedence is: parent, defaults, target.
+// The chain is the list of targets which lead to the given target and is used to detect cycles.
+func (inv *Inventory) resolveTargetExtends(target *Target, chain []string, resolved map[string]bool) error {
+	for i, name := range chain {
+		if name == target.Name {
+			cycle := append(append([]string{}, chain[i:]...), target.Name)
+			err := fmt.Errorf("target extends cycle detected: %s", strings.Join(cycle, " -> "))
+			if extending := inv.GetTarget(chain[len(chain)-1]); extending != nil {
+				return newFileError(extending.File, extending.configPath(extendsKey), err)
+			}
+			return err
+		}
+	}
+
+	if resolved[target.Name] {
+		return nil
+	}
+
+	if target.Configuration.Extends == "" {
+		target.applyDefaults()
+		resolved[target.Name] = true
+		return nil
+	}
+
+	parent := inv.GetTarget(target.Configuration.Extends)
+	if parent == nil {
+		resolved[target.Name] = true
+		return newFileError(target.File, target.configPath(extendsKey), newSentinelError(ErrTargetNotFound, "target '%s' extends target which does not exist: %s", target.Name, target.Configuration.Extends))
+	}
+
+	err := inv.resolveTargetExtends(parent, append(chain, target.Name), resolved)
 	if err != nil {
-		return nil, err
+		// a target which failed is not resolved again, hence every problem is only reported once
+		resolved[target.Name] = true
+		return err
 	}
 
-	// merge data from all classes into Data, preserving the class path.
-	// A class with path "foo.bar.class" will be added like: Data["foo"]["bar"]["baz"] = classData
-	{
-		// ensure that the loaded class-data does not conflict
-		// If two classes with the same root-key are selected, we cannot continue.
-		// We could attempt to perform a 'smart' merge or apply some precendende rules, but
-		// this will inevitably cause unexpected behaviour which is not what we want.
-		for _, class := range classes {
+	// defaults which already apply to the parent are inherited through the parent
+	var defaults []*Target
+	for _, d := range target.Defaults {
+		if !containsTarget(parent.Lineage(), d) {
+			defaults = append(defaults, d)
+		}
+	}
+	target.Defaults = defaults
 
-			// If the class name has multiple segments (foo.bar.baz), we will need to
-			// add the keys do Data, so that Data[foo][bar][baz] is where the data of the class will be added.
-			classSegments := strings.Split(class.Name, ".")
-			if len(classSegments) > 1 {
-				tmp := data
+	target.applyDefaults()
+	target.inherit(parent)
+	resolved[target.Name] = true
 
-				for _, segment := range classSegments {
+	return nil
+}
 
-					if !tmp.HasKey(segment) {
-						tmp[segment] = make(Data)
-					}
+// DataOption configures the behaviour of [Inventory.Data].
+type DataOption func(*dataOptions)
 
-					// as long as the current segment is not the RootKey, shift tmp by the segment
-					if segment != class.RootKey() {
-						tmp = tmp[segment].(Data)
-						continue
-					}
+type dataOptions struct {
+	strictClassMerge bool
+	strict           bool
+	allowedVariables []string
+	warnings         *[]string
+}
 
-					// add class data to RootKey. Since we're here, RootKey==segment, hence we can add it here.
-					if class.Data().Get(class.RootKey()) == nil {
-						continue
-					}
-					tmp[class.RootKey()] = class.Data().Get(class.RootKey())
+// WithStrictClassMerge causes [Inventory.Data] to fail if two classes contribute to the same key,
+// instead of deep-merging them.
+func WithStrictClassMerge() DataOption {
+	return func(o *dataOptions) {
+		o.strictClassMerge = true
+	}
+}
 
-				}
-			} else {
-				// class does not have a dot separator, hence we just check if the RootKey exists and add the data
-				if _, exists := data[class.RootKey()]; exists {
-					return nil, fmt.Errorf("duplicate key '%s' registered by class '%s'", class.RootKey(), class.Name)
-				}
-				data[class.RootKey()] = class.Data().Get(class.RootKey())
-			}
+// WithStrictMode causes [Inventory.Data] to fail if a variable does not point to any value, instead of ignoring it.
+// Variables which are meant for other tools (e.g. `${HOME}` inside a shell script) can be allowed with
+// glob patterns (see [path.Match]) which are matched against the variable name, e.g. `HOME` or `env_*`.
+//
+// Additionally, a warning is reported whenever a target overrides a value with a value of a different type
+// (e.g. a map with a string), see [WithWarnings].
+func WithStrictMode(allowedVariables ...string) DataOption {
+	return func(o *dataOptions) {
+		o.strict = true
+		o.allowedVariables = append(o.allowedVariables, allowedVariables...)
+	}
+}
+
+// WithWarnings collects the warnings of [Inventory.Data] into the given list.
+// Without it, warnings are dropped. The library never prints them, it's up to the caller to report them.
+func WithWarnings(warnings *[]string) DataOption {
+	return func(o *dataOptions) {
+		o.warnings = warnings
+	}
+}
+
+// warn reports the warning, see [WithWarnings].
+func (o *dataOptions) warn(format string, args ...interface{}) {
+	if o.warnings == nil {
+		return
+	}
+	(*o.warnings) = append((*o.warnings), fmt.Sprintf(format, args...))
+}
 
+// isAllowedVariable returns true if the variable matches any of the allowed patterns of the strict mode.
+func (o *dataOptions) isAllowedVariable(variable Variable) bool {
+	for _, pattern := range o.allowedVariables {
+		if matched, _ := path.Match(pattern, variable.Name); matched {
+			return true
 		}
 	}
+	return false
+}
 
-	// Merge target into Data, overwriting any existing values which were defined in classes because target data has precedence over class data.
-	// Any key which is not added to the main Data (because the keys did not already exist), will be added.
-	targetData := target.Data()
-	data = data.MergeReplace(targetData)
+// Data loads the required inventory data map given the target.
+// This is where variables and secrets are handled and eventually replaced.
+// The resulting Data is what can be passed to the templates.
+//
+// The returned Data is a copy, neither the loaded classes and targets nor the predefinedVariables are modified.
+// Hence Data can be called concurrently for different targets.
+func (inv *Inventory) Data(targetName string, predefinedVariables map[string]interface{}, skipSecretHandling, revealSecrets bool, opts ...DataOption) (data Data, err error) {
+	options := new(dataOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
 
-	// add Skipper pre-defined variables
-	if predefinedVariables == nil {
-		predefinedVariables = make(map[string]interface{})
+	target := inv.GetTarget(targetName)
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTargetNotFound, targetName)
 	}
-	predefinedVariables["target_name"] = targetName
 
-	// replace all ordinary variables (`${...}`) inside the data
-	err = ReplaceVariables(data, inv.classFiles, predefinedVariables)
+	data, _, err = inv.mergedData(target, options, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// every required placeholder (`?{required}`) must have been replaced by the target or one of its classes
+	err = inv.checkRequiredValues(target, data)
 	if err != nil {
 		return nil, err
 	}
 
+	// add Skipper pre-defined variables, the map of the caller is not modified
+	variables := make(map[string]interface{}, len(predefinedVariables)+1)
+	for name, value := range predefinedVariables {
+		variables[name] = copyValue(value)
+	}
+	variables["target_name"] = targetName
+
+	// replace all ordinary variables (`${...}`) inside the data
+	ignoredVariables, err := replaceVariables(data, inv.classFiles, variables)
+	if err != nil {
+		return nil, inv.locateError(target, err)
+	}
+
+	// in strict mode, variables which do not point to any value are most likely typos
+	if options.strict {
+		var errs ErrorList
+		for _, variable := range ignoredVariables {
+			if options.isAllowedVariable(variable) {
+				continue
+			}
+			err := &UndefinedVariableError{Variable: variable.FullName(), Path: variable.Identifier, Err: fmt.Errorf("variable is not defined")}
+			errs.add(inv.locateError(target, err))
+		}
+		if len(errs) > 0 {
+			return nil, errs.err()
+		}
+	}
+
 	// call managment
 	{
 		calls, err := FindCalls(data)
 		if err != nil {
-			return nil, err
+			return nil, inv.locateError(target, err)
 		}
 
 		for _, call := range calls {
@@ -240,23 +557,28 @@ func (inv *Inventory) Data(targetName string, predefinedVariables map[string]int
 		}
 	}
 
-	// we need to reload the target configuration as it will derive it's configuration from the Data
-	// of a previous state. Since the calls can modify the target configuration as well, we have to reload it.
-	target.ReloadConfiguration()
-
 	// secret management
 	// initialize drivers, load or create secrets and eventually replace them if `revealSecrets` is true.
 	// TODO: This flag is absolutely hacky and was introduced as hotfix
 	if !skipSecretHandling {
-		// fetch and configure secret drivers configured by the target
-		for driverName, driverConfig := range target.Configuration.Secrets.Drivers {
-			driver, err := secret.SecretDriverFactory(driverName)
+		// The target configuration is derived from the resolved Data, because variables and calls
+		// can modify the target configuration as well.
+		config, err := targetConfigFromData(data)
+		if err != nil {
+			return nil, err
+		}
+
+		// fetch and configure secret drivers configured by the target.
+		// Every call uses its own drivers, hence targets with different driver configurations do not interfere.
+		drivers := make(map[string]secret.Driver)
+		for driverName, driverConfig := range config.Secrets.Drivers {
+			driver, err := secret.NewDriver(driverName)
 			if err != nil {
 				return nil, fmt.Errorf("target contains invalid secret driver configuration: %w", err)
 			}
 
 			if drv, ok := driver.(secret.ConfigurableDriver); ok {
-				if config, ok := driverConfig.(map[string]interface{}); ok {
+				if config, ok := asData(driverConfig); ok {
 					err = drv.Configure(config)
 					if err != nil {
 						return nil, fmt.Errorf("failed to configure driver '%s': %w", driverName, err)
@@ -265,18 +587,22 @@ func (inv *Inventory) Data(targetName string, predefinedVariables map[string]int
 					return nil, fmt.Errorf("driver configuration for '%s' is not map[string]interface{}", driverName)
 				}
 			}
+			drivers[strings.ToLower(driverName)] = driver
 		}
 
 		// find all secrets or attempt to create them if an alternative action is set
-		secrets, err := FindOrCreateSecrets(data, inv.secretFiles, inv.secretPath, inv.fs)
+		// secrets might be created, which must not happen concurrently
+		inv.secretMu.Lock()
+		secrets, err := FindOrCreateSecretsWithDrivers(data, inv.secretFiles, inv.secretPath, inv.fs, drivers)
+		inv.secretMu.Unlock()
 		if err != nil {
-			return nil, err
+			return nil, inv.locateError(target, err)
 		}
 
 		// attempt load all secret files and replace the variables with the actual values if revealSecrets is true
 		for _, secret := range secrets {
 			if !secret.Exists(inv.fs) {
-				return nil, fmt.Errorf("undefined secret '%s': file does not exist: %s", secret.FullName(), secret.SecretFile.Path)
+				return nil, inv.locateError(target, &SecretNotFoundError{Secret: secret.FullName(), File: secret.SecretFile.Path, Path: secret.Identifier})
 			}
 
 			err = secret.Load(inv.fs)
@@ -295,9 +621,194 @@ func (inv *Inventory) Data(targetName string, predefinedVariables map[string]int
 		}
 	}
 
+	// the resolved data must match the JSON schemas of the classes and the target
+	err = inv.validateSchemas(target, data)
+	if err != nil {
+		return nil, err
+	}
+
 	return data, nil
 }
 
+// Removals returns all keys and list items which are removed while the classes and the target are merged,
+// either by a delete directive (`key~` or `!delete`) or by the `skipper.unset` paths of the target.
+func (inv *Inventory) Removals(targetName string) ([]Removal, error) {
+	target := inv.GetTarget(targetName)
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTargetNotFound, targetName)
+	}
+
+	_, removals, err := inv.mergedData(target, new(dataOptions), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return removals, nil
+}
+
+// mergedData merges the data of all classes used by the target and the target itself into one Data.
+// This is the state before any variables, calls or secrets are handled.
+// All keys and list items which are removed during the merge are returned as well.
+// If prov is not nil, the origin of every value is recorded, see [Inventory.Explain].
+func (inv *Inventory) mergedData(target *Target, options *dataOptions, prov *provenance) (data Data, removals []Removal, err error) {
+	data = make(Data)
+
+	// load all classes as defined by the target
+	classes, err := inv.GetUsedClasses(target.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// merge data from all classes into Data, preserving the class path.
+	// A class with path "foo.bar.baz" will be added like: Data["foo"]["bar"]["baz"] = classData
+	//
+	// Classes which contribute to the same keys are deep-merged in the order in which they are used,
+	// hence a class has precedence over all classes which are used before it.
+	// In strict mode, two classes contributing to the same key are considered an error instead.
+	for _, class := range classes {
+		if options.strictClassMerge {
+			segments := class.NameAsIdentifier()
+			classPath := append(segments[:len(segments)-1], class.RootKey())
+			if _, err := data.GetPath(classPath...); err == nil {
+				return nil, nil, fmt.Errorf("duplicate key '%s' registered by class '%s'", FormatPath(classPath), class.Name)
+			}
+		}
+
+		m := &merger{source: class.Name, removals: &removals, provenance: prov, origin: class.origin()}
+		data = m.mergeData(data, class.NestedData())
+	}
+
+	// Merge target into Data, overwriting any existing values which were defined in classes because target data has precedence over class data.
+	// Any key which is not added to the main Data (because the keys did not already exist), will be added.
+	// If the target extends other targets, their data is merged first.
+	for _, layer := range target.Lineage() {
+		m := &merger{source: layer.Name, removals: &removals, provenance: prov, origin: layer.origin()}
+		if options.strict {
+			m.typeChanged = func(path, sourcePath []interface{}, base, overlay interface{}) {
+				options.warn("target '%s' overrides '%s' (%s) with a %s at %s",
+					layer.Name, FormatPath(path), valueKind(base), valueKind(overlay), m.origin(sourcePath).location())
+			}
+		}
+		data = m.mergeData(data, layer.Data())
+	}
+
+	// finally remove everything the target wants to get rid of.
+	// All paths are resolved first, hence they point to the values of the merged Data even if a list item
+	// before them is removed (`unset: [list[0], list[1]]` removes the first two items).
+	paths := make([][]interface{}, len(target.Configuration.Unset))
+	resolved := make([][]interface{}, len(target.Configuration.Unset))
+	for i, unsetPath := range target.Configuration.Unset {
+		path, err := ParsePath(unsetPath)
+		if err != nil {
+			return nil, nil, target.lineageError(unsetKey, unsetPath, fmt.Errorf("target '%s' cannot unset '%s': %w", target.Name, unsetPath, err))
+		}
+		// negative indices are resolved, the removal points to the actual item
+		matches := data.GetAll(path...)
+		if hasNegativeIndex(path) && len(matches) == 1 {
+			path = matches[0].Path
+		}
+		paths[i] = path
+		if len(matches) == 1 {
+			resolved[i] = matches[0].Path
+		}
+	}
+	for i, unsetPath := range target.Configuration.Unset {
+		path := paths[i]
+		if resolved[i] != nil {
+			path = resolved[i]
+		}
+		value, err := data.DeletePath(path...)
+		if err != nil {
+			return nil, nil, target.lineageError(unsetKey, unsetPath, fmt.Errorf("target '%s' cannot unset '%s': %w", target.Name, unsetPath, err))
+		}
+		removals = append(removals, Removal{Path: paths[i], Source: target.Name, Value: value})
+		if resolved[i] == nil {
+			continue
+		}
+		prov.remove(resolved[i])
+
+		// the following paths no longer exist or are shifted if they point behind the removed list item
+		for j := i + 1; j < len(resolved); j++ {
+			if resolved[j] == nil {
+				continue
+			}
+			if hasPathPrefix(resolved[j], resolved[i]) {
+				return nil, nil, target.lineageError(unsetKey, target.Configuration.Unset[j], fmt.Errorf("target '%s' cannot unset '%s': it has already been removed by '%s'", target.Name, target.Configuration.Unset[j], unsetPath))
+			}
+			shiftListPath(resolved[j], resolved[i])
+		}
+	}
+
+	// the patches of the target are applied last
+	if err := applyPatches(target, &data, prov); err != nil {
+		return nil, nil, err
+	}
+
+	return data, removals, nil
+}
+
+// shiftListPath moves the path one item to the front if it points behind the removed list item.
+func shiftListPath(path, removed []interface{}) {
+	if len(removed) == 0 {
+		return
+	}
+	list := removed[:len(removed)-1]
+	index, isIndex := removed[len(removed)-1].(int)
+	if !isIndex || len(path) <= len(list) || !hasPathPrefix(path, list) {
+		return
+	}
+	if i, ok := path[len(list)].(int); ok && i > index {
+		path[len(list)] = i - 1
+	}
+}
+
+// locateError points the error to the file and position which define the value the error is caused by.
+// This is only possible for errors which know the path of the value inside the Data (see [dataPathError]),
+// all other errors are returned as they are.
+func (inv *Inventory) locateError(target *Target, err error) error {
+	var pathErr dataPathError
+	if !errors.As(err, &pathErr) {
+		return err
+	}
+
+	prov := newProvenance()
+	data, _, mergeErr := inv.mergedData(target, new(dataOptions), prov)
+	if mergeErr != nil {
+		return err
+	}
+
+	// the error is located at the value itself or at the value which contains it
+	path := pathErr.dataPath()
+	for _, value := range prov.explain(data, path) {
+		if !hasPathPrefix(path, value.Path) {
+			continue
+		}
+		file := inv.originFile(target, value.Origin)
+		if file == nil {
+			return err
+		}
+		return &InventoryError{File: file.Path, Position: value.Origin.Position, Err: err, source: file.Bytes}
+	}
+
+	return err
+}
+
+// originFile returns the file of the class or target (including its defaults and parents) of the origin.
+func (inv *Inventory) originFile(target *Target, origin Origin) *YamlFile {
+	if origin.Kind == "class" {
+		if class := inv.GetClass(origin.Source); class != nil && class.File.Path == origin.File {
+			return class.File
+		}
+		return nil
+	}
+	for _, layer := range target.Lineage() {
+		if layer.File.Path == origin.File {
+			return layer.File
+		}
+	}
+	return nil
+}
+
 // AddExternalClass can be used to dynamically create class files.
 // The given data will be written into `classFilePath`, overwriting any existing file.
 //
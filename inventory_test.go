@@ -0,0 +1,562 @@
+package skipper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+// newTestInventory creates an inventory from the given files.
+// The paths of the files are relative to the inventory root which contains the 'classes', 'targets' and 'secrets' folders.
+func newTestInventory(t *testing.T, files map[string]string) (*skipper.Inventory, error) {
+	t.Helper()
+
+	fsFiles := map[string]string{
+		"/inventory/secrets/.gitkeep": "",
+	}
+	for path, content := range files {
+		fsFiles["/inventory/"+path] = content
+	}
+	fs := newTestFs(t, fsFiles)
+
+	return skipper.NewInventory(fs, "/inventory/classes", "/inventory/targets", "/inventory/secrets")
+}
+
+// classNames returns the names of the given classes.
+func classNames(classes []*skipper.Class) []string {
+	var names []string
+	for _, class := range classes {
+		names = append(names, class.Name)
+	}
+	return names
+}
+
+func TestInventoryGetUsedClasses(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/base.yaml":        "base:\n  skipper:\n    use: [common]\n",
+		"classes/common.yaml":      "common:\n  foo: bar\n",
+		"classes/network.yaml":     "network:\n  skipper:\n    use: [base, region.west]\n",
+		"classes/region/west.yaml": "west:\n  skipper:\n    use: [common]\n",
+		"classes/app.yaml":         "app:\n  skipper:\n    use: [base]\n",
+		"targets/dev.yaml":         "target:\n  skipper:\n    use: [network, app, common]\n",
+	})
+	require.NoError(t, err)
+
+	classes, err := inventory.GetUsedClasses("dev")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"common", "base", "region.west", "network", "app"}, classNames(classes))
+}
+
+func TestInventoryGetUsedClassesErrors(t *testing.T) {
+	table := []struct {
+		TestName      string
+		Files         map[string]string
+		ExpectedError string
+	}{
+		{
+			TestName: "Cycle",
+			Files: map[string]string{
+				"classes/a.yaml":   "a:\n  skipper:\n    use: [b]\n",
+				"classes/b.yaml":   "b:\n  skipper:\n    use: [c]\n",
+				"classes/c.yaml":   "c:\n  skipper:\n    use: [a]\n",
+				"targets/dev.yaml": "target:\n  skipper:\n    use: [a]\n",
+			},
+			ExpectedError: "class import cycle detected: a -> b -> c -> a",
+		},
+		{
+			TestName: "SelfReference",
+			Files: map[string]string{
+				"classes/a.yaml":   "a:\n  skipper:\n    use: [a]\n",
+				"targets/dev.yaml": "target:\n  skipper:\n    use: [a]\n",
+			},
+			ExpectedError: "class import cycle detected: a -> a",
+		},
+		{
+			TestName: "MissingClass",
+			Files: map[string]string{
+				"classes/a.yaml":   "a:\n  skipper:\n    use: [missing]\n",
+				"targets/dev.yaml": "target:\n  skipper:\n    use: [a]\n",
+			},
+			ExpectedError: "class 'a' uses class which does not exist: missing",
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.TestName, func(t *testing.T) {
+			_, err := newTestInventory(t, tt.Files)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.ExpectedError)
+		})
+	}
+}
+
+func TestInventoryDataClassMerge(t *testing.T) {
+	files := map[string]string{
+		"classes/network/base.yaml":   "base:\n  name: base\n  tags:\n    owner: ops\n    region: none\n",
+		"classes/network/region.yaml": "region:\n  skipper:\n    use: [network.base]\n",
+		"classes/network.yaml":        "network:\n  base:\n    region: west\n  region: {}\n",
+		"classes/network/prod.yaml":   "prod:\n  skipper:\n    use: [network]\n",
+		"classes/net/base.yaml":       "base:\n  name: ${network:base:name}\n",
+		"targets/dev.yaml":            "target:\n  skipper:\n    use: [network.region, network.prod, net.base]\n  network:\n    base:\n      tags:\n        owner: dev\n",
+	}
+
+	inventory, err := newTestInventory(t, files)
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+
+	expected := skipper.Data{
+		"name": "base",
+		"tags": skipper.Data{
+			"owner":  "dev",
+			"region": "none",
+		},
+		"region": "west",
+	}
+	assert.Equal(t, expected, data["network"].(skipper.Data)["base"])
+	assert.Equal(t, "base", data["net"].(skipper.Data)["base"].(skipper.Data)["name"])
+
+	_, err = inventory.Data("dev", nil, true, false, skipper.WithStrictClassMerge())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate key 'network' registered by class 'network'")
+}
+
+func TestInventoryDataMergeDirectives(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/azure.yaml":         "azure:\n  vnet_address_space: [10.0.0.0/16]\n  dns: [1.1.1.1]\n",
+		"classes/azure/overlay.yaml": "overlay:\n  skipper:\n    use: [azure]\n",
+		"classes/region.yaml":        "region:\n  skipper:\n    use: [azure]\n",
+		"classes/west.yaml":          "west:\n  skipper:\n    use: [region]\n  foo: bar\n",
+		"targets/dev.yaml": `
+target:
+  skipper:
+    use: [west]
+  azure:
+    vnet_address_space: !replace [10.1.0.0/16]
+    dns::prepend: [8.8.8.8]
+`,
+	})
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+
+	azure := data["azure"].(skipper.Data)
+	assert.Equal(t, []interface{}{"10.1.0.0/16"}, azure["vnet_address_space"])
+	assert.Equal(t, []interface{}{"8.8.8.8", "1.1.1.1"}, azure["dns"])
+}
+
+func TestInventoryDataRemovals(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/azure.yaml": `
+azure:
+  location: westeurope
+  legacy: true
+  tags:
+    owner: ops
+  subnets: [a, b, c]
+`,
+		"classes/west.yaml": "west:\n  skipper:\n    use: [azure]\n  foo: bar\n",
+		"targets/dev.yaml": `
+target:
+  skipper:
+    use: [west]
+    unset:
+      - azure.subnets.1
+      - west.foo
+  azure:
+    legacy: !delete ~
+    tags::delete:
+    location: ~
+`,
+	})
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+
+	azure := data["azure"].(skipper.Data)
+	assert.Equal(t, skipper.Data{"location": nil, "subnets": []interface{}{"a", "c"}}, azure)
+	assert.NotContains(t, data["west"], "foo")
+
+	removals, err := inventory.Removals("dev")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []skipper.Removal{
+		{Path: []interface{}{"azure", "legacy"}, Source: "dev", Value: true},
+		{Path: []interface{}{"azure", "tags"}, Source: "dev", Value: skipper.Data{"owner": "ops"}},
+		{Path: []interface{}{"azure", "subnets", "1"}, Source: "dev", Value: "b"},
+		{Path: []interface{}{"west", "foo"}, Source: "dev", Value: "bar"},
+	}, removals)
+}
+
+func TestInventoryDataUnsetListItems(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/azure.yaml": "azure:\n  subnets: [a, b, c, d]\n  zones:\n    - name: x\n    - name: y\n      size: 1\n",
+		"targets/dev.yaml":   "target:\n  skipper:\n    use: [azure]\n    unset:\n      - azure.subnets[0]\n      - azure.subnets.1\n      - azure.subnets[-1]\n      - azure.zones[0]\n      - azure.zones[1].size\n",
+	})
+	require.NoError(t, err)
+
+	// every path points to the item of the merged Data, no matter which items are removed before
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"c"}, data["azure"].(skipper.Data)["subnets"])
+	assert.Equal(t, []interface{}{skipper.Data{"name": "y"}}, data["azure"].(skipper.Data)["zones"])
+
+	inventory, err = newTestInventory(t, map[string]string{
+		"classes/azure.yaml": "azure:\n  subnets: [a, b]\n",
+		"targets/dev.yaml":   "target:\n  skipper:\n    use: [azure]\n    unset: ['azure.subnets[0]', azure.subnets.0]\n",
+	})
+	require.NoError(t, err)
+
+	_, err = inventory.Data("dev", nil, true, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot unset 'azure.subnets.0': it has already been removed by 'azure.subnets[0]'")
+}
+
+func TestInventoryDataUnsetInvalidPath(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/azure.yaml": "azure:\n  subnets: [a]\n",
+		"targets/dev.yaml":   "target:\n  skipper:\n    use: [azure]\n    unset: [azure.subnets.5]\n",
+	})
+	require.NoError(t, err)
+
+	_, err = inventory.Data("dev", nil, true, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot unset 'azure.subnets.5'")
+}
+
+func TestInventoryTargetExtends(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/common.yaml":   "common:\n  name: common\n  list: [a]\n",
+		"classes/database.yaml": "database:\n  size: small\n",
+		"classes/cache.yaml":    "cache:\n  size: small\n",
+		"targets/base.yaml": `
+target:
+  skipper:
+    use: [common, database]
+    secrets:
+      drivers:
+        aes:
+          key: base
+    components:
+      - output_path: base
+        input_paths: [base.md]
+  database:
+    size: medium
+  stage: base
+`,
+		"targets/env/dev.yaml": `
+target:
+  skipper:
+    extends: base
+    use: [cache]
+    unset: [common.list.0]
+  stage: dev
+`,
+		"targets/env/prod.yaml": `
+target:
+  skipper:
+    extends: env.dev
+    use: [database]
+    secrets:
+      drivers:
+        azurekv:
+          key_id: prod
+  database:
+    size: large
+  stage: prod
+`,
+	})
+	require.NoError(t, err)
+
+	prod := inventory.GetTarget("env.prod")
+	require.NotNil(t, prod)
+	assert.Equal(t, "env.dev", prod.Parent.Name)
+	assert.Equal(t, "base", prod.Parent.Parent.Name)
+	assert.Len(t, prod.Lineage(), 3)
+	assert.Equal(t, []string{"common", "database", "cache"}, prod.SkipperConfig.Classes)
+	assert.Len(t, prod.Configuration.Secrets.Drivers, 2)
+	assert.Equal(t, []string{"common.list.0"}, prod.Configuration.Unset)
+
+	config, err := inventory.GetSkipperConfig("env.prod")
+	require.NoError(t, err)
+	assert.Len(t, config.Components, 1)
+
+	data, err := inventory.Data("env.prod", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", data["stage"])
+	assert.Equal(t, skipper.Data{"size": "large"}, data["database"])
+	assert.Equal(t, skipper.Data{"size": "small"}, data["cache"])
+	assert.Equal(t, skipper.Data{"name": "common", "list": []interface{}{}}, data["common"])
+
+	data, err = inventory.Data("env.dev", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", data["stage"])
+	assert.Equal(t, skipper.Data{"size": "medium"}, data["database"])
+}
+
+func TestInventoryTargetReplaceConfig(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/common.yaml":        "common:\n  list: [a]\n",
+		"classes/database.yaml":      "database:\n  size: small\n",
+		"classes/cache.yaml":         "cache:\n  size: small\n",
+		"targets/base.yaml":          "target:\n  skipper:\n    use: [common, database]\n    unset: [common.list.0]\n",
+		"targets/env/_defaults.yaml": "target:\n  skipper:\n    use: [cache]\n",
+		"targets/env/dev.yaml": `target:
+  skipper:
+    extends: base
+    use::replace: [database]
+    unset: !replace [database.size]
+`,
+		"targets/env/prod.yaml": "target:\n  skipper:\n    extends: env.dev\n    use: [cache]\n",
+	})
+	require.NoError(t, err)
+
+	// the classes of the parent and the directory defaults are replaced
+	dev := inventory.GetTarget("env.dev")
+	require.NotNil(t, dev)
+	assert.Equal(t, []string{"database"}, dev.SkipperConfig.Classes)
+	assert.Equal(t, []string{"database.size"}, dev.Configuration.Unset)
+
+	data, err := inventory.Data("env.dev", nil, true, false)
+	require.NoError(t, err)
+	assert.False(t, data.HasKey("common"))
+	assert.False(t, data.HasKey("cache"))
+	assert.Equal(t, skipper.Data{}, data["database"])
+
+	// targets which extend the target append to the replaced lists again
+	prod := inventory.GetTarget("env.prod")
+	require.NotNil(t, prod)
+	assert.Equal(t, []string{"database", "cache"}, prod.SkipperConfig.Classes)
+	assert.Equal(t, []string{"database.size"}, prod.Configuration.Unset)
+}
+
+func TestInventoryTargetExtendsErrors(t *testing.T) {
+	table := []struct {
+		TestName      string
+		Files         map[string]string
+		ExpectedError string
+	}{
+		{
+			TestName: "Cycle",
+			Files: map[string]string{
+				"targets/a.yaml": "target:\n  skipper:\n    extends: b\n",
+				"targets/b.yaml": "target:\n  skipper:\n    extends: c\n",
+				"targets/c.yaml": "target:\n  skipper:\n    extends: a\n",
+			},
+			ExpectedError: "target extends cycle detected: a -> b -> c -> a",
+		},
+		{
+			TestName: "MissingTarget",
+			Files: map[string]string{
+				"targets/a.yaml": "target:\n  skipper:\n    extends: missing\n",
+			},
+			ExpectedError: "target 'a' extends target which does not exist: missing",
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.TestName, func(t *testing.T) {
+			tt.Files["classes/common.yaml"] = "common:\n  foo: bar\n"
+			_, err := newTestInventory(t, tt.Files)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.ExpectedError)
+		})
+	}
+}
+
+func TestInventoryTargetDefaults(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/common.yaml": "common:\n  name: common\n",
+		"classes/cache.yaml":  "cache:\n  size: small\n",
+		"targets/_defaults.yaml": `
+target:
+  skipper:
+    use: [common]
+  stage: none
+  replicas: 1
+`,
+		"targets/prod/_defaults.yaml": `
+target:
+  skipper:
+    secrets:
+      drivers:
+        azurekv:
+          key_id: prod
+  stage: prod
+  replicas: 3
+`,
+		"targets/dev.yaml": `
+target:
+  skipper:
+    use: [cache]
+  stage: dev
+`,
+		"targets/prod/app.yaml": `
+target:
+  skipper:
+    use: [cache]
+  replicas: 5
+`,
+		"targets/prod/worker.yaml": `
+target:
+  skipper:
+    extends: dev
+`,
+	})
+	require.NoError(t, err)
+
+	for _, target := range inventory.GetAllTargets() {
+		assert.NotContains(t, target.Name, "_defaults")
+	}
+
+	dev := inventory.GetTarget("dev")
+	require.NotNil(t, dev)
+	assert.Equal(t, []string{"common", "cache"}, dev.SkipperConfig.Classes)
+	assert.Empty(t, dev.Configuration.Secrets.Drivers)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", data["stage"])
+	assert.Equal(t, 1, data["replicas"])
+	assert.Equal(t, skipper.Data{"name": "common"}, data["common"])
+
+	app := inventory.GetTarget("prod.app")
+	require.NotNil(t, app)
+	assert.Len(t, app.Defaults, 2)
+	assert.Contains(t, app.Configuration.Secrets.Drivers, "azurekv")
+
+	data, err = inventory.Data("prod.app", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", data["stage"])
+	assert.Equal(t, 5, data["replicas"])
+
+	// the root defaults are inherited from the parent, the prod defaults override the parent
+	worker := inventory.GetTarget("prod.worker")
+	require.NotNil(t, worker)
+	require.Len(t, worker.Defaults, 1)
+	assert.Equal(t, "prod._defaults", worker.Defaults[0].Name)
+	assert.Contains(t, worker.Configuration.Secrets.Drivers, "azurekv")
+
+	data, err = inventory.Data("prod.worker", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", data["stage"])
+	assert.Equal(t, 3, data["replicas"])
+}
+
+func TestInventoryDataIsolation(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/project.yaml": "project:\n  name: ${target_name}\n  nested:\n    list: ['${target_name}']\n",
+		"targets/a.yaml":       "target:\n  skipper:\n    use: [project]\n",
+		"targets/b.yaml":       "target:\n  skipper:\n    use: [project]\n",
+	})
+	require.NoError(t, err)
+
+	variables := map[string]interface{}{"owner": "AcmeCorp"}
+
+	a, err := inventory.Data("a", variables, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "a", a["project"].(skipper.Data)["name"])
+
+	b, err := inventory.Data("b", variables, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "b", b["project"].(skipper.Data)["name"])
+	assert.Equal(t, []interface{}{"b"}, b["project"].(skipper.Data)["nested"].(skipper.Data)["list"])
+
+	// neither the loaded classes nor the result of other targets nor the variables are modified
+	assert.Equal(t, "a", a["project"].(skipper.Data)["name"])
+	assert.Equal(t, "${target_name}", inventory.GetClass("project").Data().Get("project")["name"])
+	assert.NotContains(t, variables, "target_name")
+}
+
+func TestInventoryClassRoots(t *testing.T) {
+	library := newTestFs(t, map[string]string{
+		"/lib/network.yaml":        "network:\n  cidr: 10.0.0.0/8\n",
+		"/lib/tags.yaml":           "tags:\n  owner: platform\n  cost_center: shared\n",
+		"/lib/common/logging.yaml": "logging:\n  level: info\n",
+		"/overrides/tags.yaml":     "tags:\n  owner: overrides\n",
+		"/overrides/network.yaml":  "network:\n  cidr: 192.168.0.0/16\n",
+	})
+	fs := newTestFs(t, map[string]string{
+		"/inventory/classes/tags.yaml": "tags:\n  owner: project\n",
+		"/inventory/targets/dev.yaml":  "target:\n  skipper:\n    use: [network, tags, common.logging]\n",
+		"/inventory/secrets/.gitkeep":  "",
+	})
+
+	inventory, err := skipper.NewInventory(fs, "/inventory/classes", "/inventory/targets", "/inventory/secrets",
+		skipper.WithClassRoots(
+			skipper.ClassRoot{Name: "library", Fs: library, Path: "/lib"},
+			skipper.ClassRoot{Name: "overrides", Fs: library, Path: "/overrides"},
+		),
+	)
+	require.NoError(t, err)
+
+	tags := inventory.GetClass("tags")
+	require.NotNil(t, tags)
+	assert.Equal(t, "/inventory/classes", tags.Root)
+	require.Len(t, tags.Shadowed, 2)
+	assert.Equal(t, "library", tags.Shadowed[0].Root)
+	assert.Equal(t, "overrides", tags.Shadowed[1].Root)
+	assert.Equal(t, "overrides", inventory.GetClass("network").Root)
+	assert.Equal(t, "library", inventory.GetClass("common.logging").Root)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, skipper.Data{"owner": "project"}, data["tags"])
+	assert.Equal(t, skipper.Data{"cidr": "192.168.0.0/16"}, data["network"])
+	assert.Equal(t, skipper.Data{"logging": skipper.Data{"level": "info"}}, data["common"])
+
+	_, err = skipper.NewInventory(fs, "/inventory/classes", "/inventory/targets", "/inventory/secrets",
+		skipper.WithClassRoots(skipper.ClassRoot{Name: "missing", Path: "/missing"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing")
+}
+
+func TestInventoryDataStrictMode(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/app.yaml": "app:\n  name: app\n  script: echo ${HOME}\n  ports:\n    http: 80\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [app]\n  app:\n    ports: 8080\n    image: ${app:nmae}\n",
+	})
+	require.NoError(t, err)
+
+	// without strict mode, undefined variables are kept as they are
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "${app:nmae}", data["app"].(skipper.Data)["image"])
+
+	_, err = inventory.Data("dev", nil, true, false, skipper.WithStrictMode(), skipper.WithWarnings(new([]string)))
+	require.Error(t, err)
+	var errs skipper.ErrorList
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	assert.Contains(t, err.Error(), "reference to invalid variable '${HOME}'")
+	assert.Contains(t, err.Error(), "/inventory/targets/dev.yaml:6:5: reference to invalid variable '${app:nmae}': variable is not defined")
+
+	var warnings []string
+	_, err = inventory.Data("dev", nil, true, false, skipper.WithStrictMode("HOME"), skipper.WithWarnings(&warnings))
+	require.Error(t, err)
+	var variableErr *skipper.UndefinedVariableError
+	require.True(t, errors.As(err, &variableErr))
+	assert.Equal(t, "${app:nmae}", variableErr.Variable)
+	assert.Equal(t, []interface{}{"app", "image"}, variableErr.Path)
+	assert.Equal(t, []string{"target 'dev' overrides 'app.ports' (map) with a number at /inventory/targets/dev.yaml:5:5"}, warnings)
+}
+
+func TestInventoryDataStrictModeListItems(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/app.yaml": "app:\n  containers:\n    - name: a\n      port: 80\n    - name: b\n      port: 81\n",
+		"targets/dev.yaml": "target:\n  skipper:\n    use: [app]\n  app:\n    containers::merge:\n      - name: b\n        port: http\n",
+	})
+	require.NoError(t, err)
+
+	// the position is the one of the item inside the target, not the one of the merged item
+	var warnings []string
+	_, err = inventory.Data("dev", nil, true, false, skipper.WithStrictMode(), skipper.WithWarnings(&warnings))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"target 'dev' overrides 'app.containers[1].port' (number) with a string at /inventory/targets/dev.yaml:7:9"}, warnings)
+}
@@ -0,0 +1,384 @@
+package skipper
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergeStrategy defines how a value is merged into an already existing value of the same key.
+type mergeStrategy int
+
+const (
+	// mergeDefault merges maps recursively and appends lists. Any other value is replaced.
+	mergeDefault mergeStrategy = iota
+	// mergeReplace replaces the existing value, maps and lists are not merged at all.
+	mergeReplace
+	// mergeAppend appends the list to the existing list.
+	mergeAppend
+	// mergePrepend prepends the list to the existing list.
+	mergePrepend
+	// mergeByKey merges lists of maps by an identity field. Items with the same identity are merged recursively,
+	// all other items are appended.
+	mergeByKey
+	// mergeDelete removes the existing value, the value of the directive itself is ignored.
+	mergeDelete
+)
+
+// Merge directives are reserved key suffixes which select the merge strategy of the value.
+// Instead of a key suffix, the value can also be tagged (e.g. `foo: !replace [a, b]`).
+//
+//	foo::replace: [...]      replace the existing value
+//	foo::append: [...]       append to the existing list (default)
+//	foo::prepend: [...]      prepend to the existing list
+//	foo::merge: [...]        merge the list of maps by the 'name' field of the items
+//	foo::merge=id: [...]     merge the list of maps by the 'id' field of the items
+//	foo::delete: ~           remove the existing value
+//
+// All other keys are used as they are. A key which would otherwise be read as directive
+// is escaped with a backslash, `foo\::replace` is the plain key `foo::replace`.
+const (
+	mergeDirectiveSep     = "::"
+	mergeDirectiveEscape  = `\`
+	mergeReplaceDirective = "replace"
+	mergeAppendDirective  = "append"
+	mergePrependDirective = "prepend"
+	mergeDeleteDirective  = "delete"
+	mergeByKeyDirective   = "merge"
+
+	// defaultMergeIdentityKey is the identity field used by `::merge` and the `!merge` tag.
+	defaultMergeIdentityKey = "name"
+)
+
+// mergeKeyRegex matches keys with a merge directive (`foo::replace`), including escaped ones (`foo\::replace`).
+var mergeKeyRegex = regexp.MustCompile(`^(.+?)(\\?)::(replace|append|prepend|delete|merge(?:=(\w+))?)$`)
+
+// mergeTags maps the merge directive tags to their key suffix.
+var mergeTags = map[string]string{
+	"!replace": mergeDirectiveSep + mergeReplaceDirective,
+	"!append":  mergeDirectiveSep + mergeAppendDirective,
+	"!prepend": mergeDirectiveSep + mergePrependDirective,
+	"!merge":   mergeDirectiveSep + mergeByKeyDirective,
+	"!delete":  mergeDirectiveSep + mergeDeleteDirective,
+}
+
+// mergeDirective is the parsed merge directive of a key.
+type mergeDirective struct {
+	strategy    mergeStrategy
+	identityKey string
+}
+
+// parseMergeKey splits the given key into the actual key and its merge directive.
+// Keys without a directive are returned unchanged, escaped directives are unescaped.
+func parseMergeKey(key string) (string, mergeDirective) {
+	match := mergeKeyRegex.FindStringSubmatch(key)
+	if match == nil {
+		return key, mergeDirective{}
+	}
+	if match[2] == mergeDirectiveEscape {
+		return match[1] + mergeDirectiveSep + match[3], mergeDirective{}
+	}
+
+	switch match[3] {
+	case mergeReplaceDirective:
+		return match[1], mergeDirective{strategy: mergeReplace}
+	case mergeAppendDirective:
+		return match[1], mergeDirective{strategy: mergeAppend}
+	case mergePrependDirective:
+		return match[1], mergeDirective{strategy: mergePrepend}
+	case mergeDeleteDirective:
+		return match[1], mergeDirective{strategy: mergeDelete}
+	}
+
+	identityKey := match[4]
+	if identityKey == "" {
+		identityKey = defaultMergeIdentityKey
+	}
+	return match[1], mergeDirective{strategy: mergeByKey, identityKey: identityKey}
+}
+
+// Removal describes a value which has been removed from the Data.
+type Removal struct {
+	// Path of the removed value.
+	Path []interface{}
+	// Source is the name of the class or target which removed the value.
+	Source string
+	// Value is the value which was removed.
+	Value interface{}
+}
+
+// merger merges Data while keeping track of everything which is removed.
+type merger struct {
+	// source is the name of the class or target which is merged
+	source   string
+	removals *[]Removal
+	// provenance records the origin of every merged value if it is not nil, see [Inventory.Explain].
+	provenance *provenance
+	// origin returns the origin of the value at the given path inside the merged source, it is required if provenance is set.
+	origin func(path []interface{}) Origin
+	// typeChanged is called, if it is not nil, whenever an existing value is replaced with a value of a different type.
+	// The sourcePath points to the new value inside the merged source.
+	// Values which are replaced explicitly (`!replace`) or with null are not reported.
+	typeChanged func(path, sourcePath []interface{}, base, overlay interface{})
+}
+
+// mergeData merges overlay into base, see [Data.MergeReplace].
+// Every value removed by a delete directive is appended to removals, which may be nil.
+func mergeData(base, overlay Data, source string, removals *[]Removal) Data {
+	m := &merger{source: source, removals: removals}
+	return m.mergeData(base, overlay)
+}
+
+// mergeData merges overlay into base, see [Data.MergeReplace].
+func (m *merger) mergeData(base, overlay Data) Data {
+	return m.merge(base, overlay, mergeDirective{}, nil, nil).(Data)
+}
+
+// merge merges overlay into base, following the given directive.
+// The merge directives of all keys inside overlay are applied and removed, even if there is nothing to merge into.
+// Neither base nor overlay are modified, maps and lists are always newly created.
+// The path points to the merged value, sourcePath to the overlay inside the merged source.
+// Both only differ if list items end up at a different index than they have in the source.
+func (m *merger) merge(base, overlay interface{}, directive mergeDirective, path, sourcePath []interface{}) interface{} {
+	switch overlayValue := overlay.(type) {
+	case map[string]interface{}:
+		return m.merge(base, Data(overlayValue), directive, path, sourcePath)
+
+	case Data:
+		out := make(Data)
+
+		baseValue, isMap := asData(base)
+		if isMap && directive.strategy != mergeReplace {
+			for key, value := range baseValue {
+				out[key] = value
+			}
+		} else if base != nil {
+			m.clearProvenance(path)
+		}
+		m.checkType(path, sourcePath, base, overlay, directive)
+
+		for key, value := range overlayValue {
+			key, directive := parseMergeKey(key)
+			keyPath := appendPath(path, key)
+			keySourcePath := appendPath(sourcePath, key)
+
+			if directive.strategy == mergeDelete {
+				if existing, exists := out[key]; exists {
+					m.recordRemoval(keyPath, existing)
+					m.clearProvenance(keyPath)
+					delete(out, key)
+				}
+				continue
+			}
+
+			out[key] = m.merge(out[key], value, directive, keyPath, keySourcePath)
+		}
+		return out
+
+	case []interface{}:
+		if _, isMap := asData(base); isMap {
+			m.clearProvenance(path)
+		}
+		m.recordProvenance(path, sourcePath)
+		m.checkType(path, sourcePath, base, overlay, directive)
+
+		baseValue, isList := base.([]interface{})
+		if isList && directive.strategy == mergeByKey {
+			return m.mergeListByKey(baseValue, overlayValue, directive.identityKey, path, sourcePath)
+		}
+
+		// the items of base keep their origin, unless they are replaced
+		offset := 0
+		if isList {
+			switch directive.strategy {
+			case mergeReplace:
+				m.clearItemProvenance(path, base)
+			case mergePrepend:
+				m.shiftProvenance(path, 0, len(overlayValue))
+			default:
+				offset = len(baseValue)
+			}
+		}
+
+		items := make([]interface{}, len(overlayValue))
+		for i, item := range overlayValue {
+			items[i] = m.merge(nil, item, mergeDirective{}, appendPath(path, offset+i), appendPath(sourcePath, i))
+		}
+		if !isList {
+			return items
+		}
+
+		switch directive.strategy {
+		case mergeReplace:
+			return items
+		case mergePrepend:
+			return append(items, baseValue...)
+		default:
+			return append(append([]interface{}{}, baseValue...), items...)
+		}
+
+	default:
+		if _, isMap := asData(base); isMap {
+			m.clearProvenance(path)
+		}
+		m.clearItemProvenance(path, base)
+		m.recordProvenance(path, sourcePath)
+		m.checkType(path, sourcePath, base, overlay, directive)
+		return overlay
+	}
+}
+
+// mergeListByKey merges two lists of maps by the given identity key.
+// Items of overlay which have the same identity as an item of base are merged into that item,
+// all other items are appended. Merge directives inside the overlay items are applied.
+func (m *merger) mergeListByKey(base, overlay []interface{}, identityKey string, path, sourcePath []interface{}) []interface{} {
+	out := append([]interface{}{}, base...)
+
+	identity := func(item interface{}) (string, bool) {
+		itemData, ok := asData(item)
+		if !ok {
+			return "", false
+		}
+		value, exists := itemData[identityKey]
+		if !exists {
+			return "", false
+		}
+		return fmt.Sprint(value), true
+	}
+
+	index := make(map[string]int)
+	for i, item := range out {
+		if id, ok := identity(item); ok {
+			index[id] = i
+		}
+	}
+
+	for j, item := range overlay {
+		id, ok := identity(item)
+		if i, exists := index[id]; ok && exists {
+			out[i] = m.merge(out[i], item, mergeDirective{}, appendPath(path, i), appendPath(sourcePath, j))
+			continue
+		}
+		if ok {
+			index[id] = len(out)
+		}
+		out = append(out, m.merge(nil, item, mergeDirective{}, appendPath(path, len(out)), appendPath(sourcePath, j)))
+	}
+
+	return out
+}
+
+// checkType reports if the overlay changes the type of the existing base value, see [merger.typeChanged].
+// Required placeholders (`?{required}`) can be filled in with any type.
+func (m *merger) checkType(path, sourcePath []interface{}, base, overlay interface{}, directive mergeDirective) {
+	if m.typeChanged == nil || base == nil || overlay == nil || directive.strategy == mergeReplace {
+		return
+	}
+	if s, isString := base.(string); isString && requiredRegex.MatchString(s) {
+		return
+	}
+	if valueKind(base) != valueKind(overlay) {
+		m.typeChanged(path, sourcePath, base, overlay)
+	}
+}
+
+// valueKind returns a human readable kind of the value (e.g. `map`, `list` or `string`).
+// All numbers are of the same kind.
+func valueKind(value interface{}) string {
+	switch value.(type) {
+	case Data, map[string]interface{}:
+		return "map"
+	case []interface{}:
+		return "list"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return "number"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func (m *merger) recordRemoval(path []interface{}, value interface{}) {
+	if m.removals == nil {
+		return
+	}
+	(*m.removals) = append((*m.removals), Removal{Path: path, Source: m.source, Value: value})
+}
+
+// recordProvenance records that the value at path is set by the value at sourcePath inside the merged source.
+func (m *merger) recordProvenance(path, sourcePath []interface{}) {
+	if m.provenance == nil {
+		return
+	}
+	m.provenance.set(path, m.origin(sourcePath))
+}
+
+// clearProvenance forgets the origins of all values at and below path, because they have been removed.
+func (m *merger) clearProvenance(path []interface{}) {
+	if m.provenance == nil {
+		return
+	}
+	m.provenance.clear(path)
+}
+
+// clearItemProvenance forgets the origins of all items if base is a list which is replaced.
+// The origin of the list itself is kept, it is overridden.
+func (m *merger) clearItemProvenance(path []interface{}, base interface{}) {
+	list, isList := base.([]interface{})
+	if m.provenance == nil || !isList {
+		return
+	}
+	for i := range list {
+		m.provenance.clear(appendPath(path, i))
+	}
+}
+
+// shiftProvenance moves the origins of all items of the list at path, starting at index from, by delta.
+func (m *merger) shiftProvenance(path []interface{}, from, delta int) {
+	if m.provenance == nil {
+		return
+	}
+	m.provenance.shift(path, from, delta)
+}
+
+// appendPath returns a copy of path with the element appended.
+func appendPath(path []interface{}, element interface{}) []interface{} {
+	out := make([]interface{}, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, element)
+}
+
+// asData returns the value as Data if it is a map.
+func asData(value interface{}) (Data, bool) {
+	switch v := value.(type) {
+	case Data:
+		return v, true
+	case map[string]interface{}:
+		return Data(v), true
+	}
+	return nil, false
+}
+
+// applyMergeTags converts merge directive tags (e.g. `!replace`) on mapping values
+// into the corresponding merge directive key suffix and removes the tag.
+func applyMergeTags(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if suffix, ok := mergeTags[value.Tag]; ok {
+				key.Value += suffix
+				value.Tag = ""
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		applyMergeTags(child)
+	}
+}
@@ -0,0 +1,154 @@
+package skipper_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestDataMergeReplace(t *testing.T) {
+	base := skipper.Data{
+		"scalar": "base",
+		"list":   []interface{}{"a", "b"},
+		"map": skipper.Data{
+			"keep": "base",
+			"list": []interface{}{"a"},
+		},
+		"subnets": []interface{}{
+			skipper.Data{"name": "aks", "prefix": "10.0.0.0/24", "endpoints": []interface{}{"kv"}},
+			skipper.Data{"name": "appgw", "prefix": "10.0.1.0/24"},
+		},
+	}
+
+	table := []struct {
+		TestName string
+		Overlay  skipper.Data
+		Key      string
+		Expected interface{}
+	}{
+		{
+			TestName: "DefaultScalar",
+			Overlay:  skipper.Data{"scalar": "overlay"},
+			Key:      "scalar",
+			Expected: "overlay",
+		},
+		{
+			TestName: "DefaultList",
+			Overlay:  skipper.Data{"list": []interface{}{"c"}},
+			Key:      "list",
+			Expected: []interface{}{"a", "b", "c"},
+		},
+		{
+			TestName: "Append",
+			Overlay:  skipper.Data{"list::append": []interface{}{"c"}},
+			Key:      "list",
+			Expected: []interface{}{"a", "b", "c"},
+		},
+		{
+			TestName: "Prepend",
+			Overlay:  skipper.Data{"list::prepend": []interface{}{"c"}},
+			Key:      "list",
+			Expected: []interface{}{"c", "a", "b"},
+		},
+		{
+			TestName: "ReplaceList",
+			Overlay:  skipper.Data{"list::replace": []interface{}{"c"}},
+			Key:      "list",
+			Expected: []interface{}{"c"},
+		},
+		{
+			TestName: "ReplaceMap",
+			Overlay:  skipper.Data{"map::replace": skipper.Data{"new": "overlay"}},
+			Key:      "map",
+			Expected: skipper.Data{"new": "overlay"},
+		},
+		{
+			TestName: "NestedDirective",
+			Overlay:  skipper.Data{"map": skipper.Data{"list::replace": []interface{}{"b"}}},
+			Key:      "map",
+			Expected: skipper.Data{"keep": "base", "list": []interface{}{"b"}},
+		},
+		{
+			TestName: "DirectiveWithoutBase",
+			Overlay:  skipper.Data{"new::replace": skipper.Data{"nested::append": []interface{}{"a"}}},
+			Key:      "new",
+			Expected: skipper.Data{"nested": []interface{}{"a"}},
+		},
+		{
+			TestName: "MergeByKey",
+			Overlay: skipper.Data{"subnets::merge=name": []interface{}{
+				skipper.Data{"name": "aks", "prefix": "10.1.0.0/24", "endpoints::replace": []interface{}{"sql"}},
+				skipper.Data{"name": "db", "prefix": "10.1.2.0/24"},
+				"not-a-map",
+			}},
+			Key: "subnets",
+			Expected: []interface{}{
+				skipper.Data{"name": "aks", "prefix": "10.1.0.0/24", "endpoints": []interface{}{"sql"}},
+				skipper.Data{"name": "appgw", "prefix": "10.0.1.0/24"},
+				skipper.Data{"name": "db", "prefix": "10.1.2.0/24"},
+				"not-a-map",
+			},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.TestName, func(t *testing.T) {
+			merged := base.MergeReplace(tt.Overlay)
+			assert.Equal(t, tt.Expected, merged[tt.Key])
+		})
+	}
+
+	// the merged data must not be altered
+	assert.Equal(t, []interface{}{"a", "b"}, base["list"])
+	assert.Equal(t, skipper.Data{"keep": "base", "list": []interface{}{"a"}}, base["map"])
+}
+
+func TestDataMergeReplacePlainKeys(t *testing.T) {
+	base := skipper.Data{"c++": []interface{}{"a"}, "a!": "base", "user@host": []interface{}{skipper.Data{"host": "a"}}}
+	overlay := skipper.Data{
+		"c++":            []interface{}{"b"},
+		"a!":             "overlay",
+		"user@host":      []interface{}{skipper.Data{"host": "a"}},
+		"hi^":            "x",
+		"list~":          "y",
+		"key\\::replace": "escaped",
+	}
+
+	merged := base.MergeReplace(overlay)
+	assert.Equal(t, skipper.Data{
+		"c++":          []interface{}{"a", "b"},
+		"a!":           "overlay",
+		"user@host":    []interface{}{skipper.Data{"host": "a"}, skipper.Data{"host": "a"}},
+		"hi^":          "x",
+		"list~":        "y",
+		"key::replace": "escaped",
+	}, merged)
+}
+
+func TestYamlFileMergeTags(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/test.yaml", []byte(`
+foo:
+  replaced: !replace [a]
+  appended: !append [b]
+  prepended: !prepend [c]
+  merged: !merge
+    - name: d
+`), 0644)
+
+	file, err := skipper.NewYamlFile("/test.yaml")
+	require.NoError(t, err)
+	require.NoError(t, file.Load(fs))
+
+	expected := skipper.Data{
+		"replaced::replace":  []interface{}{"a"},
+		"appended::append":   []interface{}{"b"},
+		"prepended::prepend": []interface{}{"c"},
+		"merged::merge":      []interface{}{skipper.Data{"name": "d"}},
+	}
+	assert.Equal(t, expected, file.Data["foo"])
+}
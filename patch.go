@@ -0,0 +1,455 @@
+package skipper
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const patchesKey string = "patches"
+
+// PatchOperation is a single operation of a JSON Patch (RFC 6902), see [Data.ApplyPatch].
+type PatchOperation struct {
+	// Op is one of 'add', 'remove', 'replace', 'move', 'copy' or 'test'.
+	Op string `yaml:"op" json:"op"`
+	// Path is a JSON pointer (RFC 6901) like `/network/subnets/0`.
+	Path string `yaml:"path" json:"path"`
+	// From is the JSON pointer of the source value, it is only used by 'move' and 'copy'.
+	From string `yaml:"from,omitempty" json:"from,omitempty"`
+	// Value is used by 'add', 'replace' and 'test'.
+	Value interface{} `yaml:"value" json:"value"`
+}
+
+// PatchError is returned if an operation of a JSON Patch cannot be applied.
+type PatchError struct {
+	// Index of the failing operation inside the patch, starting at 0.
+	Index int
+	Op    string
+	Path  string
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch operation %d (%s '%s') failed: %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ParsePatch parses a JSON Patch document. Because JSON is valid YAML, the patch may be written in YAML as well.
+func ParsePatch(in []byte) ([]PatchOperation, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(in, &document); err != nil {
+		return nil, err
+	}
+	list, ok := document.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("patch must be a list of operations, got %s", valueKind(document))
+	}
+	return patchOperations(list)
+}
+
+// patchOperations converts the decoded list of a JSON Patch into operations and checks that they are complete.
+func patchOperations(list []interface{}) ([]PatchOperation, error) {
+	operations := make([]PatchOperation, 0, len(list))
+	for i, item := range list {
+		fields, ok := asData(item)
+		if !ok {
+			return nil, &PatchError{Index: i, Err: fmt.Errorf("operation must be a map, got %s", valueKind(item))}
+		}
+
+		var operation PatchOperation
+		for key, target := range map[string]*string{"op": &operation.Op, "path": &operation.Path, "from": &operation.From} {
+			value, exists := fields[key]
+			if !exists {
+				continue
+			}
+			if *target, ok = value.(string); !ok {
+				return nil, &PatchError{Index: i, Err: fmt.Errorf("'%s' must be a string, got %s", key, valueKind(value))}
+			}
+		}
+		operation.Value = fields["value"]
+
+		var required []string
+		switch operation.Op {
+		case "add", "replace", "test":
+			required = []string{"path", "value"}
+		case "remove":
+			required = []string{"path"}
+		case "move", "copy":
+			required = []string{"path", "from"}
+		default:
+			return nil, &PatchError{Index: i, Op: operation.Op, Path: operation.Path, Err: fmt.Errorf("unknown operation")}
+		}
+		for _, key := range required {
+			if _, exists := fields[key]; !exists {
+				return nil, &PatchError{Index: i, Op: operation.Op, Path: operation.Path, Err: fmt.Errorf("missing '%s'", key)}
+			}
+		}
+
+		operations = append(operations, operation)
+	}
+	return operations, nil
+}
+
+// ApplyPatch applies the operations of a JSON Patch (RFC 6902) in order.
+// The patch is atomic: if any operation fails, a [PatchError] with the index of the operation is returned
+// and the Data is left unchanged.
+func (d *Data) ApplyPatch(patch []PatchOperation) error {
+	patched := d.Copy()
+	if patched == nil {
+		patched = make(Data)
+	}
+
+	for i, operation := range patch {
+		err := patched.applyOperation(operation)
+		if err != nil {
+			return &PatchError{Index: i, Op: operation.Op, Path: operation.Path, Err: err}
+		}
+	}
+
+	*d = patched
+	return nil
+}
+
+// applyOperation applies a single operation of a JSON Patch.
+func (d *Data) applyOperation(operation PatchOperation) error {
+	switch operation.Op {
+	case "add":
+		return d.patchAdd(operation.Path, copyValue(operation.Value))
+
+	case "remove":
+		path, err := d.pointerPath(operation.Path, false)
+		if err != nil {
+			return err
+		}
+		if len(path) == 0 {
+			return fmt.Errorf("cannot remove the whole document")
+		}
+		_, err = d.DeletePath(path...)
+		return err
+
+	case "replace":
+		path, err := d.pointerPath(operation.Path, false)
+		if err != nil {
+			return err
+		}
+		if _, err := d.GetPath(path...); err != nil {
+			return err
+		}
+		return d.patchSet(path, copyValue(operation.Value))
+
+	case "move":
+		if operation.From == operation.Path {
+			return nil
+		}
+		if strings.HasPrefix(operation.Path, operation.From+"/") {
+			return fmt.Errorf("cannot move '%s' into itself", operation.From)
+		}
+		from, err := d.pointerPath(operation.From, false)
+		if err != nil {
+			return err
+		}
+		if len(from) == 0 {
+			return fmt.Errorf("cannot move the whole document")
+		}
+		value, err := d.DeletePath(from...)
+		if err != nil {
+			return err
+		}
+		return d.patchAdd(operation.Path, value)
+
+	case "copy":
+		from, err := d.pointerPath(operation.From, false)
+		if err != nil {
+			return err
+		}
+		value, err := d.GetPath(from...)
+		if err != nil {
+			return err
+		}
+		return d.patchAdd(operation.Path, copyValue(value))
+
+	case "test":
+		path, err := d.pointerPath(operation.Path, false)
+		if err != nil {
+			return err
+		}
+		value, err := d.GetPath(path...)
+		if err != nil {
+			return err
+		}
+		actual, err := jsonValue(value)
+		if err != nil {
+			return err
+		}
+		expected, err := jsonValue(operation.Value)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			return fmt.Errorf("value is %s, expected %s", compactValue(actual), compactValue(expected))
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown operation")
+	}
+}
+
+// patchAdd adds the value at the JSON pointer. Keys of maps are added or replaced, items of lists are inserted.
+func (d *Data) patchAdd(pointer string, value interface{}) error {
+	path, err := d.pointerPath(pointer, true)
+	if err != nil {
+		return err
+	}
+	if len(path) == 0 {
+		return d.patchSet(path, value)
+	}
+
+	parent, err := d.GetPath(path[:len(path)-1]...)
+	if err != nil {
+		return err
+	}
+	list, isList := parent.([]interface{})
+	if !isList {
+		return d.patchSet(path, value)
+	}
+
+	index := path[len(path)-1].(int)
+	inserted := make([]interface{}, 0, len(list)+1)
+	inserted = append(append(append(inserted, list[:index]...), value), list[index:]...)
+	return d.patchSet(path[:len(path)-1], inserted)
+}
+
+// patchSet replaces the value at the path, the parent of the value must exist.
+// An empty path replaces the whole Data, which requires the value to be a map.
+func (d *Data) patchSet(path []interface{}, value interface{}) error {
+	if len(path) == 0 {
+		data, ok := asData(value)
+		if !ok {
+			return fmt.Errorf("document must be a map, got %s", valueKind(value))
+		}
+		*d = data
+		return nil
+	}
+	_, err := setValue(*d, path, 0, value, false)
+	return err
+}
+
+// pointerPath converts the JSON pointer (RFC 6901) into a path of the Data.
+// Whether a segment is a key or a list index depends on the value it is applied to, hence all parents must exist.
+// If add is true, the last segment may point right behind the end of a list, either with its length or with `-`.
+func (d Data) pointerPath(pointer string, add bool) ([]interface{}, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer '%s': must be empty or start with '/'", pointer)
+	}
+
+	var path []interface{}
+	var node interface{} = d
+	segments := strings.Split(pointer[1:], "/")
+	for i, segment := range segments {
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		last := i == len(segments)-1
+
+		switch n := node.(type) {
+		case Data, map[string]interface{}:
+			nodeMap, _ := asData(n)
+			path = append(path, segment)
+			child, exists := nodeMap[segment]
+			if !exists && !last {
+				return nil, &KeyNotFoundError{Key: segment, Path: path}
+			}
+			node = child
+
+		case []interface{}:
+			length := len(n)
+			if add && last {
+				length++
+			}
+			index, err := strconv.Atoi(segment)
+			switch {
+			case segment == "-" && add && last:
+				index = len(n)
+			case err != nil || (len(segment) > 1 && segment[0] == '0') || segment[0] == '-':
+				return nil, fmt.Errorf("invalid list index '%s' at %s", segment, FormatPath(path))
+			case index >= length:
+				return nil, fmt.Errorf("index %d out of range at %s", index, FormatPath(path))
+			}
+			path = append(path, index)
+			if index < len(n) {
+				node = n[index]
+			}
+
+		default:
+			return nil, fmt.Errorf("cannot resolve '%s' inside %s at %s", segment, valueKind(node), FormatPath(path))
+		}
+	}
+
+	return path, nil
+}
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7396).
+// Maps are merged recursively, null values remove the key and any other value (including lists) replaces the existing value.
+func (d *Data) ApplyMergePatch(patch Data) {
+	if *d == nil {
+		*d = make(Data)
+	}
+	mergePatch(*d, patch)
+}
+
+// mergePatch applies the merge patch to the map and returns it.
+func mergePatch(target Data, patch Data) Data {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		patchMap, isMap := asData(value)
+		if !isMap {
+			target[key] = copyValue(value)
+			continue
+		}
+		existing, isMap := asData(target[key])
+		if !isMap {
+			existing = make(Data)
+		}
+		target[key] = mergePatch(existing, patchMap)
+	}
+	return target
+}
+
+// targetPatch is a patch file declared by a target (`skipper.patches`).
+type targetPatch struct {
+	// item is the entry of the patch inside `skipper.patches`.
+	item string
+	file *File
+	// operations is set if the file contains a JSON Patch, otherwise merge is set.
+	operations []PatchOperation
+	merge      Data
+}
+
+// apply applies the patch to the Data.
+func (p *targetPatch) apply(data *Data) error {
+	if p.merge != nil {
+		data.ApplyMergePatch(p.merge)
+		return nil
+	}
+	return data.ApplyPatch(p.operations)
+}
+
+// loadPatches loads the patch files declared by the target itself (not by its parents or defaults).
+// The paths are relative to the directory of the target file.
+// A file which contains a list is a JSON Patch, a file which contains a map is a JSON Merge Patch.
+func (inv *Inventory) loadPatches(target *Target) error {
+	listPath := target.configPath(patchesKey)
+	value, err := target.File.Data.GetPath(listPath...)
+	if err != nil {
+		return nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return newFileError(target.File, listPath, fmt.Errorf("patches must be a list of files"))
+	}
+
+	errs := make(ErrorList, 0)
+	target.patches = nil
+	for i, item := range items {
+		itemPath := appendPath(listPath, i)
+		name, ok := item.(string)
+		if !ok || name == "" {
+			errs.add(newFileError(target.File, itemPath, fmt.Errorf("patch must be a file path")))
+			continue
+		}
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(target.File.Path), path)
+		}
+
+		file, err := NewFile(path)
+		if err != nil {
+			errs.add(newFileError(target.File, itemPath, err))
+			continue
+		}
+		if err := file.Load(inv.fs); err != nil {
+			errs.add(newFileError(target.File, itemPath, fmt.Errorf("target '%s' cannot load patch: %w", target.Name, err)))
+			continue
+		}
+
+		patch := &targetPatch{item: name, file: file}
+		var document interface{}
+		err = yaml.Unmarshal(file.Bytes, &document)
+		if err == nil {
+			switch typed := document.(type) {
+			case []interface{}:
+				patch.operations, err = patchOperations(typed)
+			case map[string]interface{}:
+				patch.merge = Data(typed)
+			default:
+				err = fmt.Errorf("patch must be a list (JSON Patch) or a map (JSON Merge Patch), got %s", valueKind(document))
+			}
+		}
+		if err != nil {
+			errs.add(newFileError(target.File, itemPath, fmt.Errorf("invalid patch %s: %w", file.Path, err)))
+			continue
+		}
+		target.patches = append(target.patches, patch)
+	}
+
+	return errs.err()
+}
+
+// applyPatches applies the patches of the target lineage, starting with the top-most parent.
+// If prov is not nil, all values changed by a patch are recorded with the patch file as origin.
+func applyPatches(target *Target, data *Data, prov *provenance) error {
+	for _, layer := range target.Lineage() {
+		for _, patch := range layer.patches {
+			var before Data
+			if prov != nil {
+				before = data.Copy()
+			}
+
+			if err := patch.apply(data); err != nil {
+				itemPath := findListItem(layer.File, layer.configPath(patchesKey), patch.item)
+				return newFileError(layer.File, itemPath, fmt.Errorf("target '%s' cannot apply patch %s: %w", layer.Name, patch.file.Path, err))
+			}
+
+			if prov != nil {
+				origin := Origin{Source: layer.Name, Kind: "patch", File: patch.file.Path}
+				for _, change := range before.Diff(*data) {
+					if change.Type == ChangeRemoved || change.Type == ChangeTypeChanged {
+						prov.clear(change.Path)
+					}
+					if change.Type != ChangeRemoved {
+						recordValue(prov, change.Path, change.To, origin)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// recordValue records the origin of the value and, if it is a map or a list, of all values inside it.
+func recordValue(prov *provenance, path []interface{}, value interface{}, origin Origin) {
+	if valueMap, isMap := asData(value); isMap {
+		for key, child := range valueMap {
+			recordValue(prov, appendPath(path, key), child, origin)
+		}
+		return
+	}
+	prov.set(path, origin)
+	if list, isList := value.([]interface{}); isList {
+		for i, item := range list {
+			recordValue(prov, appendPath(path, i), item, origin)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package skipper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestDataApplyPatch(t *testing.T) {
+	data := skipper.Data{
+		"network": map[string]interface{}{"cidr": "10.0.0.0/8", "dns": []interface{}{"1.1.1.1", "8.8.8.8"}},
+		"a/b":     map[string]interface{}{"~c": 1},
+		"legacy":  true,
+	}
+
+	patch, err := skipper.ParsePatch([]byte(`[
+		{"op": "test", "path": "/a~1b/~0c", "value": 1},
+		{"op": "add", "path": "/network/dns/1", "value": "9.9.9.9"},
+		{"op": "add", "path": "/network/dns/-", "value": "4.4.4.4"},
+		{"op": "replace", "path": "/network/cidr", "value": "10.1.0.0/16"},
+		{"op": "remove", "path": "/legacy"},
+		{"op": "copy", "from": "/network/dns/0", "path": "/primary"},
+		{"op": "move", "from": "/a~1b", "path": "/network/extra"},
+		{"op": "add", "path": "/empty", "value": null}
+	]`))
+	require.NoError(t, err)
+
+	err = data.ApplyPatch(patch)
+	require.NoError(t, err)
+	assert.Equal(t, skipper.Data{
+		"network": map[string]interface{}{
+			"cidr":  "10.1.0.0/16",
+			"dns":   []interface{}{"1.1.1.1", "9.9.9.9", "8.8.8.8", "4.4.4.4"},
+			"extra": map[string]interface{}{"~c": 1},
+		},
+		"primary": "1.1.1.1",
+		"empty":   nil,
+	}, data)
+}
+
+func TestDataApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch []skipper.PatchOperation
+		err   string
+	}{
+		{
+			name:  "failing test",
+			patch: []skipper.PatchOperation{{Op: "add", Path: "/b", Value: 2}, {Op: "test", Path: "/a", Value: "1"}},
+			err:   `patch operation 1 (test '/a') failed: value is 1, expected "1"`,
+		},
+		{
+			name:  "replace missing key",
+			patch: []skipper.PatchOperation{{Op: "replace", Path: "/missing", Value: 1}},
+			err:   "patch operation 0 (replace '/missing') failed: key not found: missing",
+		},
+		{
+			name:  "index out of range",
+			patch: []skipper.PatchOperation{{Op: "add", Path: "/list/3", Value: 1}},
+			err:   "patch operation 0 (add '/list/3') failed: index 3 out of range at list",
+		},
+		{
+			name:  "invalid index",
+			patch: []skipper.PatchOperation{{Op: "remove", Path: "/list/01"}},
+			err:   "patch operation 0 (remove '/list/01') failed: invalid list index '01' at list",
+		},
+		{
+			name:  "move into itself",
+			patch: []skipper.PatchOperation{{Op: "move", From: "/list", Path: "/list/0"}},
+			err:   "patch operation 0 (move '/list/0') failed: cannot move '/list' into itself",
+		},
+		{
+			name:  "invalid pointer",
+			patch: []skipper.PatchOperation{{Op: "remove", Path: "a"}},
+			err:   "patch operation 0 (remove 'a') failed: invalid JSON pointer 'a': must be empty or start with '/'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := skipper.Data{"a": 1, "list": []interface{}{"x", "y"}}
+			err := data.ApplyPatch(tt.patch)
+			assert.EqualError(t, err, tt.err)
+
+			var patchErr *skipper.PatchError
+			assert.True(t, errors.As(err, &patchErr))
+			// the patch is atomic
+			assert.Equal(t, skipper.Data{"a": 1, "list": []interface{}{"x", "y"}}, data)
+		})
+	}
+
+	_, err := skipper.ParsePatch([]byte("- op: add\n  path: /a\n  value: 1\n- op: replace\n  path: /b\n"))
+	assert.EqualError(t, err, "patch operation 1 (replace '/b') failed: missing 'value'")
+	_, err = skipper.ParsePatch([]byte("- op: merge\n  path: /a\n"))
+	assert.EqualError(t, err, "patch operation 0 (merge '/a') failed: unknown operation")
+	_, err = skipper.ParsePatch([]byte("op: add\n"))
+	assert.EqualError(t, err, "patch must be a list of operations, got map")
+}
+
+func TestDataApplyMergePatch(t *testing.T) {
+	data := skipper.Data{
+		"title":   "Goodbye!",
+		"author":  map[string]interface{}{"givenName": "John", "familyName": "Doe"},
+		"tags":    []interface{}{"example", "sample"},
+		"content": "This will be unchanged",
+	}
+
+	data.ApplyMergePatch(skipper.Data{
+		"title":       "Hello!",
+		"phoneNumber": "+01-123-456-7890",
+		"author":      map[string]interface{}{"familyName": nil},
+		"tags":        []interface{}{"example"},
+	})
+	assert.Equal(t, skipper.Data{
+		"title":       "Hello!",
+		"author":      skipper.Data{"givenName": "John"},
+		"tags":        []interface{}{"example"},
+		"content":     "This will be unchanged",
+		"phoneNumber": "+01-123-456-7890",
+	}, data)
+}
+
+func TestInventoryTargetPatches(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/network.yaml": "network:\n  cidr: 10.0.0.0/8\n  dns: [1.1.1.1]\n  legacy: true\n",
+		"targets/dev.yaml": `target:
+  skipper:
+    use: [network]
+    patches:
+      - ../patches/dns.json
+      - ../patches/merge.yaml
+  name: ${target_name}
+`,
+		"patches/dns.json":   `[{"op": "add", "path": "/network/dns/0", "value": "9.9.9.9"}, {"op": "add", "path": "/network/name", "value": "${target_name}"}]`,
+		"patches/merge.yaml": "network:\n  legacy: ~\n  cidr: 10.1.0.0/16\n",
+	})
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", nil, false, false)
+	require.NoError(t, err)
+	network, err := data.GetPath("network")
+	require.NoError(t, err)
+	assert.Equal(t, skipper.Data{"cidr": "10.1.0.0/16", "dns": []interface{}{"9.9.9.9", "1.1.1.1"}, "name": "dev"}, network)
+
+	explained, err := inventory.Explain("dev", "network.cidr")
+	require.NoError(t, err)
+	require.Len(t, explained, 1)
+	assert.Equal(t, "patch", explained[0].Origin.Kind)
+	assert.Equal(t, "/inventory/patches/merge.yaml", explained[0].Origin.File)
+	assert.Equal(t, "class", explained[0].Overrides[0].Kind)
+}
+
+func TestInventoryTargetPatchErrors(t *testing.T) {
+	_, err := newTestInventory(t, map[string]string{
+		"classes/network.yaml": "network:\n  cidr: 10.0.0.0/8\n",
+		"targets/dev.yaml":     "target:\n  skipper:\n    use: [network]\n    patches: [../patches/missing.yaml, ../patches/invalid.yaml]\n",
+		"patches/invalid.yaml": "just a string\n",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "targets/dev.yaml:4:15: target 'dev' cannot load patch")
+	assert.Contains(t, err.Error(), "targets/dev.yaml:4:40: invalid patch /inventory/patches/invalid.yaml: patch must be a list (JSON Patch) or a map (JSON Merge Patch), got string")
+
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/network.yaml": "network:\n  cidr: 10.0.0.0/8\n",
+		"targets/dev.yaml":     "target:\n  skipper:\n    use: [network]\n    patches:\n      - ../patches/dns.yaml\n",
+		"patches/dns.yaml":     "- op: test\n  path: /network/cidr\n  value: 10.0.0.0/8\n- op: remove\n  path: /network/dns\n",
+	})
+	require.NoError(t, err)
+
+	_, err = inventory.Data("dev", nil, false, false)
+	var patchErr *skipper.PatchError
+	require.True(t, errors.As(err, &patchErr))
+	assert.Equal(t, 1, patchErr.Index)
+	assert.Contains(t, err.Error(), "targets/dev.yaml:5:9: target 'dev' cannot apply patch /inventory/patches/dns.yaml: patch operation 1 (remove '/network/dns') failed")
+}
@@ -0,0 +1,343 @@
+package skipper
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Paths point to values inside [Data]. A path is a list of segments: strings are map keys, ints are list indices
+// and [Wildcard] matches every key or index. The string representation of a path is parsed with [ParsePath]:
+//
+//	foo.bar          keys are separated by dots
+//	foo.list[0]      list indices are written in brackets
+//	foo.list[-1]     negative indices count from the end of the list (-1 is the last item)
+//	foo."a.b"        keys which contain dots, hyphens or any other special character are quoted
+//	foo["a.b"]       quoted keys can also be written in brackets
+//	foo.*.name       wildcards match every key of a map, see [Data.GetAll]
+//	foo.list[*]      or every item of a list
+//
+// Unquoted keys which only consist of digits (`foo.list.0`) are still keys, but they can be used to index lists as well.
+
+// Wildcard is the path segment which matches every key of a map and every item of a list.
+var Wildcard = wildcard{}
+
+type wildcard struct{}
+
+func (wildcard) String() string {
+	return "*"
+}
+
+// pathKeyRegex matches keys which can be written without quotes.
+var pathKeyRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// ParsePath parses the string representation of a path (e.g. `foo.bar[0]."baz.qux"`) into its segments.
+// An empty string is the empty path, which points to the Data itself.
+func ParsePath(path string) ([]interface{}, error) {
+	return parsePath(path, ".")
+}
+
+// parsePath parses the path, every character of separators separates two keys.
+func parsePath(path string, separators string) ([]interface{}, error) {
+	p := &pathParser{input: path, separators: separators}
+	segments, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid path '%s': %w", path, err)
+	}
+	return segments, nil
+}
+
+// pathParser parses the path grammar, see [ParsePath].
+type pathParser struct {
+	input      string
+	separators string
+	pos        int
+}
+
+func (p *pathParser) parse() ([]interface{}, error) {
+	var segments []interface{}
+	if p.input == "" {
+		return segments, nil
+	}
+
+	// a key is expected at the start of the path and after every separator
+	expectKey := true
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == '[':
+			segment, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+			expectKey = false
+
+		case expectKey:
+			segment, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+			expectKey = false
+
+		case strings.IndexByte(p.separators, c) >= 0:
+			p.pos++
+			expectKey = true
+
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' at offset %d", c, p.pos)
+		}
+	}
+
+	if expectKey {
+		return nil, fmt.Errorf("missing key at the end of the path")
+	}
+	return segments, nil
+}
+
+// parseKey parses a quoted key, a wildcard or a plain key.
+func (p *pathParser) parseKey() (interface{}, error) {
+	c := p.input[p.pos]
+	if c == '"' || c == '\'' {
+		return p.parseQuoted()
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != '[' && strings.IndexByte(p.separators, p.input[p.pos]) < 0 {
+		p.pos++
+	}
+	key := p.input[start:p.pos]
+	if key == "" {
+		return nil, fmt.Errorf("empty key at offset %d", start)
+	}
+	if key == "*" {
+		return Wildcard, nil
+	}
+	if strings.ContainsAny(key, `"'*]`) {
+		return nil, fmt.Errorf("key '%s' at offset %d must be quoted", key, start)
+	}
+	return key, nil
+}
+
+// parseQuoted parses a key enclosed in single or double quotes. Quotes and backslashes can be escaped with a backslash.
+func (p *pathParser) parseQuoted() (string, error) {
+	start := p.pos
+	quote := p.input[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input):
+			b.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated quote at offset %d", start)
+}
+
+// parseBracket parses an index (`[0]`, `[-1]`), a wildcard (`[*]`) or a quoted key (`["a.b"]`).
+func (p *pathParser) parseBracket() (interface{}, error) {
+	start := p.pos
+	p.pos++
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("unterminated bracket at offset %d", start)
+	}
+
+	var segment interface{}
+	if c := p.input[p.pos]; c == '"' || c == '\'' {
+		key, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		segment = key
+	} else {
+		end := strings.IndexByte(p.input[p.pos:], ']')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated bracket at offset %d", start)
+		}
+		content := p.input[p.pos : p.pos+end]
+		p.pos += end
+		if content == "*" {
+			segment = Wildcard
+		} else {
+			index, err := strconv.Atoi(content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index '%s' at offset %d", content, start)
+			}
+			segment = index
+		}
+	}
+
+	if p.pos >= len(p.input) || p.input[p.pos] != ']' {
+		return nil, fmt.Errorf("unterminated bracket at offset %d", start)
+	}
+	p.pos++
+	return segment, nil
+}
+
+// FormatPath returns the string representation of the path, which can be parsed with [ParsePath].
+// Indices are written in brackets and keys which contain special characters are quoted.
+func FormatPath(path []interface{}) string {
+	var b strings.Builder
+	for i, segment := range path {
+		switch s := segment.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", s)
+			continue
+		case wildcard:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString("*")
+			continue
+		}
+
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		key := fmt.Sprint(segment)
+		if pathKeyRegex.MatchString(key) {
+			b.WriteString(key)
+		} else {
+			b.WriteString(quotePathKey(key))
+		}
+	}
+	return b.String()
+}
+
+// quotePathKey quotes the key with double quotes, quotes and backslashes inside the key are escaped.
+func quotePathKey(key string) string {
+	key = strings.ReplaceAll(key, `\`, `\\`)
+	key = strings.ReplaceAll(key, `"`, `\"`)
+	return `"` + key + `"`
+}
+
+// listIndex returns the index of the list item the path segment points to.
+// Negative indices count from the end of the list.
+func listIndex(segment interface{}, length int) (int, error) {
+	index, ok := segment.(int)
+	if !ok {
+		var err error
+		index, err = strconv.Atoi(fmt.Sprint(segment))
+		if err != nil {
+			return 0, fmt.Errorf("unexpected integer path element '%v' (%T)", segment, segment)
+		}
+	}
+	if index < 0 {
+		index += length
+	}
+	if index < 0 || index >= length {
+		return 0, fmt.Errorf("path index out of range: %v", segment)
+	}
+	return index, nil
+}
+
+// hasWildcard returns true if any segment of the path is a [Wildcard].
+func hasWildcard(path []interface{}) bool {
+	for _, segment := range path {
+		if segment == Wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNegativeIndex returns true if any segment of the path is a negative list index.
+func hasNegativeIndex(path []interface{}) bool {
+	for _, segment := range path {
+		if index, isIndex := segment.(int); isIndex && index < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PathValue is a value inside [Data] and the path which points to it.
+type PathValue struct {
+	Path  []interface{}
+	Value interface{}
+}
+
+// GetAll returns all values which match the path, see [Data.GetPath].
+// In contrast to GetPath, the path may contain wildcards. Keys are matched in sorted order, list items in their order.
+// Paths which do not exist are skipped, hence the result is empty if nothing matches.
+// The returned paths do not contain wildcards and negative indices.
+func (d Data) GetAll(path ...interface{}) []PathValue {
+	var out []PathValue
+
+	var match func(value interface{}, matched, rest []interface{})
+	match = func(value interface{}, matched, rest []interface{}) {
+		if len(rest) == 0 {
+			out = append(out, PathValue{Path: matched, Value: value})
+			return
+		}
+		segment := rest[0]
+
+		if data, isMap := asData(value); isMap {
+			if segment == Wildcard {
+				for _, key := range data.sortedKeys() {
+					match(data[key], appendPath(matched, key), rest[1:])
+				}
+				return
+			}
+			key := fmt.Sprint(segment)
+			if child, exists := data[key]; exists {
+				match(child, appendPath(matched, key), rest[1:])
+			}
+			return
+		}
+
+		if list, isList := value.([]interface{}); isList {
+			if segment == Wildcard {
+				for i, item := range list {
+					match(item, appendPath(matched, i), rest[1:])
+				}
+				return
+			}
+			// indices which are out of range do not match anything
+			if index, err := listIndex(segment, len(list)); err == nil {
+				match(list[index], appendPath(matched, index), rest[1:])
+			}
+		}
+	}
+
+	match(d, nil, path)
+	return out
+}
+
+// Lookup returns the value at the path given as string, e.g. `foo.bar[0]`, see [ParsePath] and [Data.GetPath].
+func (d Data) Lookup(path string) (interface{}, error) {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return d.GetPath(segments...)
+}
+
+// LookupAll returns all values which match the path given as string, e.g. `foo.*.name`, see [ParsePath] and [Data.GetAll].
+func (d Data) LookupAll(path string) ([]PathValue, error) {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return d.GetAll(segments...), nil
+}
+
+// Assign sets the value at the path given as string, e.g. `foo.bar[0]`, see [ParsePath] and [Data.SetPath].
+func (d *Data) Assign(path string, value interface{}) error {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	return d.SetPath(value, segments...)
+}
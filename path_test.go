@@ -0,0 +1,122 @@
+package skipper_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestParsePath(t *testing.T) {
+	table := []struct {
+		TestName      string
+		Path          string
+		Expected      []interface{}
+		ExpectedError string
+	}{
+		{TestName: "Empty", Path: "", Expected: nil},
+		{TestName: "Dotted", Path: "foo.bar.0", Expected: []interface{}{"foo", "bar", "0"}},
+		{TestName: "Index", Path: "foo.list[0].name", Expected: []interface{}{"foo", "list", 0, "name"}},
+		{TestName: "NegativeIndex", Path: "foo[-1][2]", Expected: []interface{}{"foo", -1, 2}},
+		{TestName: "QuotedKey", Path: `foo."a.b".'c-d'`, Expected: []interface{}{"foo", "a.b", "c-d"}},
+		{TestName: "BracketKey", Path: `foo["a.b"]["say \"hi\""]`, Expected: []interface{}{"foo", "a.b", `say "hi"`}},
+		{TestName: "Wildcards", Path: "foo.*.list[*]", Expected: []interface{}{"foo", skipper.Wildcard, "list", skipper.Wildcard}},
+		{TestName: "QuotedWildcard", Path: `foo."*"`, Expected: []interface{}{"foo", "*"}},
+		{TestName: "EmptyKey", Path: "foo..bar", ExpectedError: "empty key at offset 4"},
+		{TestName: "TrailingDot", Path: "foo.", ExpectedError: "missing key at the end of the path"},
+		{TestName: "InvalidIndex", Path: "foo[bar]", ExpectedError: "invalid index 'bar' at offset 3"},
+		{TestName: "UnterminatedBracket", Path: "foo[0", ExpectedError: "unterminated bracket at offset 3"},
+		{TestName: "UnterminatedQuote", Path: `foo."bar`, ExpectedError: "unterminated quote at offset 4"},
+		{TestName: "MissingSeparator", Path: `foo[0]bar`, ExpectedError: "unexpected character 'b' at offset 6"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.TestName, func(t *testing.T) {
+			path, err := skipper.ParsePath(tt.Path)
+			if tt.ExpectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.Expected, path)
+
+			// formatting and parsing again results in the same path
+			parsed, err := skipper.ParsePath(skipper.FormatPath(path))
+			require.NoError(t, err)
+			assert.Equal(t, path, parsed)
+		})
+	}
+}
+
+func TestFormatPath(t *testing.T) {
+	assert.Equal(t, "foo.list[0].name", skipper.FormatPath([]interface{}{"foo", "list", 0, "name"}))
+	assert.Equal(t, `foo."a.b".c-d`, skipper.FormatPath([]interface{}{"foo", "a.b", "c-d"}))
+	assert.Equal(t, `"say \"hi\"".*[-1]`, skipper.FormatPath([]interface{}{`say "hi"`, skipper.Wildcard, -1}))
+	assert.Equal(t, "", skipper.FormatPath(nil))
+}
+
+func TestDataPathExpressions(t *testing.T) {
+	data, err := skipper.NewData(map[string]interface{}{
+		"subnets": []interface{}{
+			map[string]interface{}{"name": "a", "cidr": "10.0.1.0/24"},
+			map[string]interface{}{"name": "b", "cidr": "10.0.2.0/24"},
+		},
+		"tags": map[string]interface{}{
+			"app.kubernetes.io/name": "web",
+			"owner":                  "ops",
+		},
+	})
+	require.NoError(t, err)
+
+	value, err := data.Lookup("subnets[-1].name")
+	require.NoError(t, err)
+	assert.Equal(t, "b", value)
+
+	value, err = data.Lookup(`tags."app.kubernetes.io/name"`)
+	require.NoError(t, err)
+	assert.Equal(t, "web", value)
+
+	_, err = data.Lookup("subnets[2]")
+	assert.EqualError(t, err, "path index out of range: 2")
+	_, err = data.Lookup("subnets[*].name")
+	assert.Error(t, err)
+
+	err = data.Assign("subnets[-2].name", "first")
+	require.NoError(t, err)
+	value, err = data.GetPath("subnets", 0, "name")
+	require.NoError(t, err)
+	assert.Equal(t, "first", value)
+
+	matches, err := data.LookupAll("subnets[*].name")
+	require.NoError(t, err)
+	assert.Equal(t, []skipper.PathValue{
+		{Path: []interface{}{"subnets", 0, "name"}, Value: "first"},
+		{Path: []interface{}{"subnets", 1, "name"}, Value: "b"},
+	}, matches)
+
+	matches = data.GetAll("tags", skipper.Wildcard)
+	require.Len(t, matches, 2)
+	assert.Equal(t, []interface{}{"tags", "app.kubernetes.io/name"}, matches[0].Path)
+	assert.Equal(t, []interface{}{"tags", "owner"}, matches[1].Path)
+
+	assert.Empty(t, data.GetAll("missing", skipper.Wildcard))
+}
+
+func TestVariablePathExpressions(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/network.yaml": "network:\n  subnets:\n    - name: a\n    - name: b\n  tags:\n    app.kubernetes.io/name: web\n",
+		"targets/dev.yaml":     "target:\n  skipper:\n    use: [network]\n  first: ${network:subnets[0]:name}\n  last: ${network:subnets[-1]:name}\n  app: ${network:tags:\"app.kubernetes.io/name\"}\n  legacy: ${network:subnets:1:name}\n  terraform: ${var.location}\n",
+	})
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "a", data["first"])
+	assert.Equal(t, "b", data["last"])
+	assert.Equal(t, "web", data["app"])
+	assert.Equal(t, "b", data["legacy"])
+	assert.Equal(t, "${var.location}", data["terraform"])
+}
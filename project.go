@@ -0,0 +1,302 @@
+package skipper
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the default name of the Skipper project file.
+const ProjectFileName = "skipper.yaml"
+
+// ProjectVersion is the latest version of the project file format which is supported.
+const ProjectVersion = 1
+
+// Project describes a Skipper project, usually loaded from a `skipper.yaml` at the repository root.
+// It declares where the inventory and the templates are located and where the compiled output goes to.
+// All paths are relative to the directory of the project file.
+//
+//	version: 1
+//	inventory:
+//	  classes: inventory/classes
+//	  targets: inventory/targets
+//	  secrets: inventory/secrets
+//	  class_roots:
+//	    - name: shared
+//	      path: ../shared/classes
+//	vendor:
+//	  path: vendor
+//	  sources:
+//	    - name: networking
+//	      url: file:///repos/classes.git
+//	      ref: v1.0.0
+//	      path: networking
+//	templates: templates
+//	output: compiled
+//	variables:
+//	  company_name: AcmeCorp
+//	functions:
+//	  - myTemplateFunc
+//	targets:
+//	  prod:
+//	    output: deploy/prod
+type Project struct {
+	// Version of the project file format.
+	Version int `yaml:"version"`
+	// Inventory holds the paths of the inventory.
+	Inventory ProjectInventoryConfig `yaml:"inventory"`
+	// Templates is the template root path.
+	Templates string `yaml:"templates"`
+	// Output is the output root path. Every target is compiled into `<output>/<target>`, unless configured otherwise.
+	Output string `yaml:"output"`
+	// Variables are the default predefined variables which are passed to [Inventory.Data].
+	Variables map[string]interface{} `yaml:"variables,omitempty"`
+	// Functions is the list of user template functions which are enabled.
+	// If the list is empty, all user functions passed to [Project.NewTemplater] are enabled.
+	// User functions only exist when skipper is used as a library, the skipper binary rejects projects which enable any.
+	Functions []string `yaml:"functions,omitempty"`
+	// Targets allows to configure target specific settings.
+	Targets map[string]ProjectTargetConfig `yaml:"targets,omitempty"`
+	// Vendor configures external class sources, see [Project.FetchVendor].
+	Vendor ProjectVendorConfig `yaml:"vendor,omitempty"`
+
+	fs       afero.Fs
+	rootPath string
+}
+
+// ProjectInventoryConfig are the paths which make up the inventory.
+type ProjectInventoryConfig struct {
+	Classes string `yaml:"classes"`
+	Targets string `yaml:"targets"`
+	Secrets string `yaml:"secrets"`
+	// ClassRoots are additional class roots (e.g. shared class libraries) which are loaded before the Classes.
+	// Classes of later roots shadow the classes of earlier roots, see [WithClassRoots].
+	ClassRoots []ProjectClassRoot `yaml:"class_roots,omitempty"`
+	// Schemas is the directory which contains the JSON schemas of classes, see [WithSchemaPath].
+	Schemas string `yaml:"schemas,omitempty"`
+}
+
+// ProjectClassRoot is an additional class root of the inventory.
+type ProjectClassRoot struct {
+	// Name identifies the root, it defaults to the path.
+	Name string `yaml:"name,omitempty"`
+	// Path of the root directory.
+	Path string `yaml:"path"`
+}
+
+// ProjectVendorConfig configures the external class sources of the project.
+// Every source is vendored into `<path>/<name>` and loaded as class root before all other class roots.
+type ProjectVendorConfig struct {
+	// Path is the vendor directory.
+	Path string `yaml:"path"`
+	// Sources are the external class sources.
+	Sources []VendorSource `yaml:"sources"`
+}
+
+// ProjectTargetConfig holds target specific project settings.
+type ProjectTargetConfig struct {
+	// Output is the output root of the target. It overwrites the default `<output>/<target>`.
+	Output string `yaml:"output"`
+}
+
+// DefaultProject returns a project with the default layout, rooted at `rootPath`.
+//
+//	<rootPath>/inventory/{classes,targets,secrets}
+//	<rootPath>/templates
+//	<rootPath>/compiled
+func DefaultProject(fs afero.Fs, rootPath string) *Project {
+	return &Project{
+		Version: ProjectVersion,
+		Inventory: ProjectInventoryConfig{
+			Classes: filepath.Join("inventory", "classes"),
+			Targets: filepath.Join("inventory", "targets"),
+			Secrets: filepath.Join("inventory", "secrets"),
+		},
+		Templates: "templates",
+		Output:    "compiled",
+		Vendor:    ProjectVendorConfig{Path: "vendor"},
+		fs:        fs,
+		rootPath:  rootPath,
+	}
+}
+
+// LoadProject loads the project file at the given path.
+// If path is a directory, the [ProjectFileName] inside that directory is loaded.
+// Every path which is not set in the file is set to the default of [DefaultProject].
+func LoadProject(fs afero.Fs, path string) (*Project, error) {
+	if fs == nil {
+		return nil, fmt.Errorf("fs cannot be nil")
+	}
+	if path == "" {
+		return nil, fmt.Errorf("path cannot be empty")
+	}
+
+	isDir, err := afero.IsDir(fs, path)
+	if err == nil && isDir {
+		path = filepath.Join(path, ProjectFileName)
+	}
+
+	file, err := NewFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = file.Load(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	project := DefaultProject(fs, filepath.Dir(path))
+	project.Version = 0
+
+	decoder := yaml.NewDecoder(bytes.NewReader(file.Bytes))
+	decoder.KnownFields(true)
+	err = decoder.Decode(project)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project file %s: %w", path, err)
+	}
+
+	if project.Version == 0 {
+		return nil, fmt.Errorf("invalid project file %s: version is missing", path)
+	}
+	if project.Version > ProjectVersion {
+		return nil, fmt.Errorf("invalid project file %s: version %d is not supported, the latest supported version is %d", path, project.Version, ProjectVersion)
+	}
+
+	return project, nil
+}
+
+// Fs returns the filesystem of the project.
+func (p *Project) Fs() afero.Fs {
+	return p.fs
+}
+
+// RootPath returns the directory of the project file to which all paths are relative.
+func (p *Project) RootPath() string {
+	return p.rootPath
+}
+
+// Path returns the given project-relative path relative to the working directory.
+// Absolute paths are returned unchanged.
+func (p *Project) Path(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.rootPath, path)
+}
+
+// ClassPath returns the path of the inventory classes.
+func (p *Project) ClassPath() string {
+	return p.Path(p.Inventory.Classes)
+}
+
+// TargetPath returns the path of the inventory targets.
+func (p *Project) TargetPath() string {
+	return p.Path(p.Inventory.Targets)
+}
+
+// SecretPath returns the path of the inventory secrets.
+func (p *Project) SecretPath() string {
+	return p.Path(p.Inventory.Secrets)
+}
+
+// SchemaPath returns the path of the class schemas, it is empty if no schema path is configured.
+func (p *Project) SchemaPath() string {
+	if p.Inventory.Schemas == "" {
+		return ""
+	}
+	return p.Path(p.Inventory.Schemas)
+}
+
+// TemplatePath returns the template root path.
+func (p *Project) TemplatePath() string {
+	return p.Path(p.Templates)
+}
+
+// VendorPath returns the vendor directory.
+func (p *Project) VendorPath() string {
+	return p.Path(p.Vendor.Path)
+}
+
+// VendorSourcePath returns the directory into which the vendor source with the given name is vendored.
+func (p *Project) VendorSourcePath(name string) string {
+	return filepath.Join(p.VendorPath(), name)
+}
+
+// VendorLockPath returns the path of the lock file.
+func (p *Project) VendorLockPath() string {
+	return filepath.Join(p.rootPath, VendorLockFileName)
+}
+
+// OutputPath returns the output root path of the given target.
+func (p *Project) OutputPath(targetName string) string {
+	if config, ok := p.Targets[targetName]; ok && config.Output != "" {
+		return p.Path(config.Output)
+	}
+	return filepath.Join(p.Path(p.Output), targetName)
+}
+
+// PredefinedVariables returns a copy of the configured variables which can be passed to [Inventory.Data].
+func (p *Project) PredefinedVariables() map[string]interface{} {
+	variables := make(map[string]interface{}, len(p.Variables))
+	for key, value := range p.Variables {
+		variables[key] = value
+	}
+	return variables
+}
+
+// ClassRoots returns the additional class roots of the inventory, see [WithClassRoots].
+// The vendored sources are the first roots, followed by the configured class roots.
+func (p *Project) ClassRoots() []ClassRoot {
+	var roots []ClassRoot
+	for _, source := range p.Vendor.Sources {
+		roots = append(roots, ClassRoot{Name: "vendor/" + source.Name, Fs: p.fs, Path: p.VendorSourcePath(source.Name)})
+	}
+	for _, root := range p.Inventory.ClassRoots {
+		roots = append(roots, ClassRoot{Name: root.Name, Fs: p.fs, Path: p.Path(root.Path)})
+	}
+	return roots
+}
+
+// NewInventory creates the [Inventory] as it is configured by the project.
+// If the project has vendor sources, they must be vendored as configured, see [Project.FetchVendor].
+func (p *Project) NewInventory() (*Inventory, error) {
+	if err := p.checkVendor(); err != nil {
+		return nil, err
+	}
+	options := []InventoryOption{WithClassRoots(p.ClassRoots()...)}
+	if schemaPath := p.SchemaPath(); schemaPath != "" {
+		options = append(options, WithSchemaPath(schemaPath))
+	}
+	return NewInventory(p.fs, p.ClassPath(), p.TargetPath(), p.SecretPath(), options...)
+}
+
+// NewTemplater creates the [Templater] for the given target.
+// The userFuncMap holds all user template functions which are available, but only the ones
+// enabled by the project are passed to the templater.
+func (p *Project) NewTemplater(targetName string, userFuncMap map[string]any, ignoreRegex []string) (*Templater, error) {
+	funcs, err := p.enabledFunctions(userFuncMap)
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplater(p.fs, p.TemplatePath(), p.OutputPath(targetName), funcs, ignoreRegex)
+}
+
+// enabledFunctions filters the given userFuncMap by the functions enabled in the project.
+func (p *Project) enabledFunctions(userFuncMap map[string]any) (map[string]any, error) {
+	if len(p.Functions) == 0 {
+		return userFuncMap, nil
+	}
+
+	funcs := make(map[string]any, len(p.Functions))
+	for _, name := range p.Functions {
+		fn, exists := userFuncMap[name]
+		if !exists {
+			return nil, fmt.Errorf("project enables template function '%s' which is not defined", name)
+		}
+		funcs[name] = fn
+	}
+	return funcs, nil
+}
@@ -0,0 +1,93 @@
+package skipper_test
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestLoadProject(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/repo/skipper.yaml", []byte(`
+version: 1
+inventory:
+  classes: inv/classes
+  class_roots:
+    - name: shared
+      path: ../shared/classes
+templates: /abs/templates
+variables:
+  company_name: AcmeCorp
+targets:
+  prod:
+    output: deploy/prod
+`), 0644)
+
+	project, err := skipper.LoadProject(fs, "/repo")
+	require.NoError(t, err)
+
+	assert.Equal(t, "/repo/inv/classes", project.ClassPath())
+	assert.Equal(t, []skipper.ClassRoot{{Name: "shared", Fs: fs, Path: "/shared/classes"}}, project.ClassRoots())
+	assert.Equal(t, "/repo/inventory/targets", project.TargetPath())
+	assert.Equal(t, "/repo/inventory/secrets", project.SecretPath())
+	assert.Equal(t, "/abs/templates", project.TemplatePath())
+	assert.Equal(t, "/repo/compiled/dev", project.OutputPath("dev"))
+	assert.Equal(t, "/repo/deploy/prod", project.OutputPath("prod"))
+
+	variables := project.PredefinedVariables()
+	assert.Equal(t, "AcmeCorp", variables["company_name"])
+	variables["company_name"] = "changed"
+	assert.Equal(t, "AcmeCorp", project.Variables["company_name"])
+}
+
+func TestLoadProjectErrors(t *testing.T) {
+	table := []struct {
+		TestName string
+		Content  string
+	}{
+		{TestName: "MissingVersion", Content: "templates: foo"},
+		{TestName: "UnsupportedVersion", Content: "version: 1000"},
+		{TestName: "UnknownField", Content: "version: 1\ntemplate: foo"},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.TestName, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			afero.WriteFile(fs, "/skipper.yaml", []byte(tt.Content), 0644)
+
+			_, err := skipper.LoadProject(fs, "/skipper.yaml")
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := skipper.LoadProject(afero.NewMemMapFs(), "/skipper.yaml")
+	assert.Error(t, err)
+}
+
+func TestProjectNewTemplater(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/skipper.yaml", []byte("version: 1\nfunctions: [enabled]"), 0644)
+	afero.WriteFile(fs, "/templates/file.txt", []byte(`{{ enabled }}`), 0644)
+
+	project, err := skipper.LoadProject(fs, "/skipper.yaml")
+	require.NoError(t, err)
+
+	funcs := map[string]any{
+		"enabled":  func() string { return "yes" },
+		"disabled": func() string { return "no" },
+	}
+	templater, err := project.NewTemplater("dev", funcs, nil)
+	require.NoError(t, err)
+	require.NoError(t, templater.ExecuteAll(nil, false, nil))
+
+	out, err := afero.ReadFile(fs, "/compiled/dev/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "yes", string(out))
+
+	_, err = project.NewTemplater("dev", map[string]any{}, nil)
+	assert.Error(t, err)
+}
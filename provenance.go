@@ -0,0 +1,344 @@
+package skipper
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlExtensions are the file extensions of yaml files, only for those the positions of values are known.
+var yamlExtensions = map[string]bool{"": true, ".yml": true, ".yaml": true}
+
+// Position is a location inside a file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Origin describes where a value has been set.
+type Origin struct {
+	// Source is the name of the class or target which set the value.
+	Source string
+	// Kind is either "class", "target" or "patch" (see [TargetConfig.Patches]).
+	Kind string
+	// Root is the class root of the class, see [ClassRoot]. It is empty for targets.
+	Root string
+	// File is the path of the file which contains the value.
+	File string
+	// Position of the value inside the File. It is only known for yaml files, otherwise the Line is 0.
+	Position Position
+}
+
+// String returns the origin like `class 'network' (root shared) at /path/to/network.yaml:3:5`.
+func (o Origin) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s '%s'", o.Kind, o.Source)
+	if o.Root != "" {
+		fmt.Fprintf(&b, " (root %s)", o.Root)
+	}
+	fmt.Fprintf(&b, " at %s", o.location())
+	return b.String()
+}
+
+// location returns the file and, if known, the position of the origin as `file:line:col`.
+func (o Origin) location() string {
+	if o.Position.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d", o.File, o.Position.Line, o.Position.Column)
+	}
+	return o.File
+}
+
+// Provenance explains where a single value of the [Data] of a target came from.
+type Provenance struct {
+	// Path of the value. Lists are explained as a whole and every list item on its own.
+	Path []interface{}
+	// Value as it was merged, before variables, calls and secrets are resolved.
+	Value interface{}
+	// Origin is the class or target which set the value last.
+	Origin Origin
+	// Overrides are the origins which set the value before, in the order of the merge.
+	// For lists, which are appended by default, these are all contributors of the list.
+	Overrides []Origin
+	// Variables are the variables (`${...}`) which produce the value.
+	Variables []string
+	// Calls are the calls (`%{...}`) which produce the value.
+	Calls []string
+}
+
+// String returns a human readable, multi-line explanation of the value.
+func (p Provenance) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %v\n", FormatPath(p.Path), p.Value)
+	fmt.Fprintf(&b, "  set by %s\n", p.Origin)
+	for i := len(p.Overrides) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "  overrides %s\n", p.Overrides[i])
+	}
+	for _, variable := range p.Variables {
+		fmt.Fprintf(&b, "  variable %s\n", variable)
+	}
+	for _, call := range p.Calls {
+		fmt.Fprintf(&b, "  call %s\n", call)
+	}
+	return b.String()
+}
+
+// provenance records the origin of every value while Data is merged.
+// Lists are recorded as a whole and every item on its own, maps only by the values inside them.
+type provenance struct {
+	values map[string]*Provenance
+}
+
+func newProvenance() *provenance {
+	return &provenance{values: make(map[string]*Provenance)}
+}
+
+// set records that the value at path has been set by origin.
+func (p *provenance) set(path []interface{}, origin Origin) {
+	if p == nil {
+		return
+	}
+	key := pathKey(path)
+
+	existing, exists := p.values[key]
+	if !exists {
+		p.values[key] = &Provenance{Path: append([]interface{}{}, path...), Origin: origin}
+		return
+	}
+	// a source which sets the value again does not override itself
+	if existing.Origin.Source == origin.Source && existing.Origin.Kind == origin.Kind {
+		return
+	}
+	existing.Overrides = append(existing.Overrides, existing.Origin)
+	existing.Origin = origin
+}
+
+// clear removes everything recorded at and below the path, because it does no longer exist.
+func (p *provenance) clear(path []interface{}) {
+	if p == nil {
+		return
+	}
+	for key, value := range p.values {
+		if hasPathPrefix(value.Path, path) {
+			delete(p.values, key)
+		}
+	}
+}
+
+// shift moves everything recorded for the items of the list at path, starting at index from, by delta.
+// It is used whenever items are inserted into or removed from the list.
+func (p *provenance) shift(path []interface{}, from, delta int) {
+	if p == nil || delta == 0 {
+		return
+	}
+	var shifted []*Provenance
+	for key, value := range p.values {
+		if len(value.Path) <= len(path) || !hasPathPrefix(value.Path, path) {
+			continue
+		}
+		if index, isIndex := value.Path[len(path)].(int); isIndex && index >= from {
+			delete(p.values, key)
+			value.Path[len(path)] = index + delta
+			shifted = append(shifted, value)
+		}
+	}
+	for _, value := range shifted {
+		p.values[pathKey(value.Path)] = value
+	}
+}
+
+// remove removes the value at path. If it is a list item, all following items are shifted.
+func (p *provenance) remove(path []interface{}) {
+	p.clear(path)
+	if len(path) == 0 {
+		return
+	}
+	if index, isIndex := path[len(path)-1].(int); isIndex {
+		p.shift(path[:len(path)-1], index+1, -1)
+	}
+}
+
+// pathKey returns a comparable representation of the path.
+// The path is formatted with [FormatPath], hence the key `a.b` is distinct from the path a → b
+// and the list index 0 is distinct from the key `0`.
+func pathKey(path []interface{}) string {
+	return FormatPath(path)
+}
+
+// hasPathPrefix returns true if path starts with prefix. Segments are compared by their string representation.
+func hasPathPrefix(path, prefix []interface{}) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if fmt.Sprint(path[i]) != fmt.Sprint(prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// explain returns the recorded provenance of all values at or below the path.
+// If nothing is recorded there, because the path points into a value (e.g. into a string), that value is returned.
+func (p *provenance) explain(data Data, path []interface{}) []*Provenance {
+	var out []*Provenance
+	var closest *Provenance
+	for _, value := range p.values {
+		below, above := hasPathPrefix(value.Path, path), hasPathPrefix(path, value.Path)
+		if !below && !above {
+			continue
+		}
+		// the value might have been removed by `skipper.unset`
+		current, err := data.GetPath(value.Path...)
+		if err != nil {
+			continue
+		}
+
+		explained := *value
+		explained.Value = current
+		if below {
+			out = append(out, &explained)
+		} else if closest == nil || len(value.Path) > len(closest.Path) {
+			closest = &explained
+		}
+	}
+	if len(out) == 0 && closest != nil {
+		out = append(out, closest)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return pathKey(out[i].Path) < pathKey(out[j].Path)
+	})
+
+	return out
+}
+
+// yamlPositions returns the position of every key and list item inside the yaml document.
+// The positions are indexed by the [pathKey] of their path, merge directives are stripped from the keys.
+func yamlPositions(in []byte) map[string]Position {
+	var node yaml.Node
+	if err := yaml.Unmarshal(in, &node); err != nil {
+		return nil
+	}
+	applyMergeTags(&node)
+
+	positions := make(map[string]Position)
+	indexYamlPositions(&node, nil, positions)
+	return positions
+}
+
+func indexYamlPositions(node *yaml.Node, path []interface{}, positions map[string]Position) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			indexYamlPositions(child, path, positions)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, _ := parseMergeKey(node.Content[i].Value)
+			keyPath := appendPath(path, key)
+			positions[pathKey(keyPath)] = Position{Line: node.Content[i].Line, Column: node.Content[i].Column}
+			indexYamlPositions(node.Content[i+1], keyPath, positions)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			itemPath := appendPath(path, i)
+			positions[pathKey(itemPath)] = Position{Line: child.Line, Column: child.Column}
+			indexYamlPositions(child, itemPath, positions)
+		}
+	}
+}
+
+// Position returns the position of the value at the given path inside the file.
+// The position is only known for yaml files.
+func (f *YamlFile) Position(path ...interface{}) (Position, bool) {
+	if f.positions == nil {
+		return Position{}, false
+	}
+	position, ok := f.positions[pathKey(path)]
+	return position, ok
+}
+
+// loadPositions indexes the positions of all values if the file is a yaml file.
+func (f *YamlFile) loadPositions() {
+	if yamlExtensions[strings.ToLower(filepath.Ext(f.Path))] {
+		f.positions = yamlPositions(f.Bytes)
+	}
+}
+
+// origin returns a function which creates the Origin of a path inside the merged Data.
+func (c *Class) origin() func(path []interface{}) Origin {
+	segments := c.NameAsIdentifier()
+	return func(path []interface{}) Origin {
+		origin := Origin{Source: c.Name, Kind: "class", Root: c.Root, File: c.File.Path}
+		// the class data is nested under the class path, the class file starts at the root key
+		if len(path) >= len(segments) {
+			origin.Position, _ = c.File.Position(path[len(segments)-1:]...)
+		}
+		return origin
+	}
+}
+
+// origin returns a function which creates the Origin of a path inside the merged Data.
+func (t *Target) origin() func(path []interface{}) Origin {
+	return func(path []interface{}) Origin {
+		origin := Origin{Source: t.Name, Kind: "target", File: t.File.Path}
+		origin.Position, _ = t.File.Position(append([]interface{}{targetKey}, path...)...)
+		return origin
+	}
+}
+
+// Explain returns the provenance of all values of the target Data at or below the given path, see [ParsePath].
+// An empty path explains all values. Lists are explained as a whole and every item on its own.
+//
+// For every value, the class or target (including file and position) which set it is returned,
+// as well as all classes and targets which set it before, and the variables and calls which produce it.
+// Values are returned as merged, before any variables, calls or secrets are resolved.
+func (inv *Inventory) Explain(targetName, path string) ([]*Provenance, error) {
+	target := inv.GetTarget(targetName)
+	if target == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTargetNotFound, targetName)
+	}
+
+	prov := newProvenance()
+	data, _, err := inv.mergedData(target, new(dataOptions), prov)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	explained := prov.explain(data, query)
+	if len(explained) == 0 {
+		return nil, fmt.Errorf("path '%s' does not exist in target '%s'", path, targetName)
+	}
+
+	variables, err := FindVariables(data)
+	if err != nil {
+		return nil, err
+	}
+	calls, err := FindCalls(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range explained {
+		for _, variable := range variables {
+			if hasPathPrefix(variable.Identifier, value.Path) {
+				value.Variables = append(value.Variables, variable.FullName())
+			}
+		}
+		for _, call := range calls {
+			if hasPathPrefix(call.Identifier, value.Path) {
+				value.Calls = append(value.Calls, call.FullName())
+			}
+		}
+	}
+
+	return explained, nil
+}
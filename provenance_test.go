@@ -0,0 +1,195 @@
+package skipper_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestInventoryExplain(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/network.yaml": `network:
+  cidr: 10.0.0.0/8
+  ports: [80]
+  name: ${target_name}-net
+  home: "%{env:HOME}"
+`,
+		"classes/firewall.json": `{"firewall": {"rules": []}}`,
+		"targets/dev.yaml": `target:
+  skipper:
+    use: [network, firewall]
+  network:
+    cidr: 192.168.0.0/16
+    ports: [443]
+`,
+	})
+	require.NoError(t, err)
+
+	explained, err := inventory.Explain("dev", "network.cidr")
+	require.NoError(t, err)
+	require.Len(t, explained, 1)
+
+	cidr := explained[0]
+	assert.Equal(t, []interface{}{"network", "cidr"}, cidr.Path)
+	assert.Equal(t, "192.168.0.0/16", cidr.Value)
+	assert.Equal(t, skipper.Origin{
+		Source:   "dev",
+		Kind:     "target",
+		File:     "/inventory/targets/dev.yaml",
+		Position: skipper.Position{Line: 5, Column: 5},
+	}, cidr.Origin)
+	require.Len(t, cidr.Overrides, 1)
+	assert.Equal(t, skipper.Origin{
+		Source:   "network",
+		Kind:     "class",
+		Root:     "/inventory/classes",
+		File:     "/inventory/classes/network.yaml",
+		Position: skipper.Position{Line: 2, Column: 3},
+	}, cidr.Overrides[0])
+	assert.Contains(t, cidr.String(), "set by target 'dev' at /inventory/targets/dev.yaml:5:5")
+
+	// lists are explained as a whole, every contributor is listed
+	explained, err = inventory.Explain("dev", "network.ports")
+	require.NoError(t, err)
+	require.Len(t, explained, 3)
+	assert.Equal(t, []interface{}{80, 443}, explained[0].Value)
+	assert.Equal(t, "dev", explained[0].Origin.Source)
+	assert.Equal(t, 6, explained[0].Origin.Position.Line)
+	require.Len(t, explained[0].Overrides, 1)
+	assert.Equal(t, "network", explained[0].Overrides[0].Source)
+	assert.Equal(t, 3, explained[0].Overrides[0].Position.Line)
+
+	// every item is explained on its own
+	assert.Equal(t, []interface{}{"network", "ports", 0}, explained[1].Path)
+	assert.Equal(t, "network", explained[1].Origin.Source)
+	assert.Equal(t, skipper.Position{Line: 3, Column: 11}, explained[1].Origin.Position)
+	explained, err = inventory.Explain("dev", "network.ports[1]")
+	require.NoError(t, err)
+	require.Len(t, explained, 1)
+	assert.Equal(t, 443, explained[0].Value)
+	assert.Equal(t, "dev", explained[0].Origin.Source)
+	assert.Equal(t, skipper.Position{Line: 6, Column: 13}, explained[0].Origin.Position)
+	assert.Empty(t, explained[0].Overrides)
+
+	explained, err = inventory.Explain("dev", "network")
+	require.NoError(t, err)
+	require.Len(t, explained, 6)
+	assert.Equal(t, []interface{}{"network", "home"}, explained[1].Path)
+	assert.Equal(t, []string{"%{env:HOME}"}, explained[1].Calls)
+	assert.Equal(t, []interface{}{"network", "name"}, explained[2].Path)
+	assert.Equal(t, []string{"${target_name}"}, explained[2].Variables)
+
+	// the position is only known for yaml files
+	explained, err = inventory.Explain("dev", "firewall.rules")
+	require.NoError(t, err)
+	require.Len(t, explained, 1)
+	assert.Equal(t, "/inventory/classes/firewall.json", explained[0].Origin.File)
+	assert.Equal(t, 0, explained[0].Origin.Position.Line)
+
+	_, err = inventory.Explain("dev", "network.missing")
+	assert.Error(t, err)
+	_, err = inventory.Explain("missing", "network")
+	assert.Error(t, err)
+}
+
+func TestInventoryExplainDottedKeys(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/common.yaml": "common:\n  a:\n    b: 2\n  \"0\": key\n",
+		"targets/dev.yaml": `target:
+  skipper:
+    use: [common]
+  common:
+    a.b: 1
+`,
+	})
+	require.NoError(t, err)
+
+	explained, err := inventory.Explain("dev", `common."a.b"`)
+	require.NoError(t, err)
+	require.Len(t, explained, 1)
+	assert.Equal(t, []interface{}{"common", "a.b"}, explained[0].Path)
+	assert.Equal(t, 1, explained[0].Value)
+	assert.Equal(t, "dev", explained[0].Origin.Source)
+	assert.Equal(t, skipper.Position{Line: 5, Column: 5}, explained[0].Origin.Position)
+	assert.Empty(t, explained[0].Overrides)
+
+	explained, err = inventory.Explain("dev", "common.a.b")
+	require.NoError(t, err)
+	require.Len(t, explained, 1)
+	assert.Equal(t, []interface{}{"common", "a", "b"}, explained[0].Path)
+	assert.Equal(t, 2, explained[0].Value)
+	assert.Equal(t, "common", explained[0].Origin.Source)
+	assert.Equal(t, skipper.Position{Line: 3, Column: 5}, explained[0].Origin.Position)
+	assert.Empty(t, explained[0].Overrides)
+
+	explained, err = inventory.Explain("dev", "common")
+	require.NoError(t, err)
+	require.Len(t, explained, 3)
+	assert.Equal(t, []interface{}{"common", "a.b"}, explained[0].Path)
+	assert.Equal(t, []interface{}{"common", "0"}, explained[1].Path)
+	assert.Equal(t, skipper.Position{Line: 4, Column: 3}, explained[1].Origin.Position)
+	assert.Equal(t, []interface{}{"common", "a", "b"}, explained[2].Path)
+}
+
+func TestInventoryExplainListItems(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/network.yaml": `network:
+  dns: [1.1.1.1, 8.8.8.8]
+  subnets:
+    - name: a
+      cidr: 10.0.0.0/24
+    - name: b
+      cidr: 10.0.1.0/24
+`,
+		"targets/dev.yaml": `target:
+  skipper:
+    use: [network]
+    unset: [network.dns.0]
+  network:
+    dns::prepend: [9.9.9.9, 4.4.4.4]
+    subnets::merge=name:
+      - name: c
+        cidr: 10.0.2.0/24
+      - name: b
+        cidr: 10.1.1.0/24
+`,
+	})
+	require.NoError(t, err)
+
+	// the prepended items shift the items of the class, the first one is removed by unset
+	explained, err := inventory.Explain("dev", "network.dns")
+	require.NoError(t, err)
+	require.Len(t, explained, 4)
+	assert.Equal(t, []interface{}{"4.4.4.4", "1.1.1.1", "8.8.8.8"}, explained[0].Value)
+	assert.Equal(t, "4.4.4.4", explained[1].Value)
+	assert.Equal(t, skipper.Position{Line: 6, Column: 29}, explained[1].Origin.Position)
+	assert.Equal(t, "1.1.1.1", explained[2].Value)
+	assert.Equal(t, skipper.Position{Line: 2, Column: 9}, explained[2].Origin.Position)
+	assert.Equal(t, "8.8.8.8", explained[3].Value)
+	assert.Equal(t, "network", explained[3].Origin.Source)
+
+	// the items are merged by name, the new item is appended
+	explained, err = inventory.Explain("dev", "network.subnets[1].cidr")
+	require.NoError(t, err)
+	require.Len(t, explained, 1)
+	assert.Equal(t, "10.1.1.0/24", explained[0].Value)
+	assert.Equal(t, skipper.Position{Line: 11, Column: 9}, explained[0].Origin.Position)
+	require.Len(t, explained[0].Overrides, 1)
+	assert.Equal(t, skipper.Position{Line: 7, Column: 7}, explained[0].Overrides[0].Position)
+
+	explained, err = inventory.Explain("dev", "network.subnets[2]")
+	require.NoError(t, err)
+	require.Len(t, explained, 2)
+	assert.Equal(t, []interface{}{"network", "subnets", 2, "cidr"}, explained[0].Path)
+	assert.Equal(t, skipper.Position{Line: 9, Column: 9}, explained[0].Origin.Position)
+	assert.Equal(t, "c", explained[1].Value)
+	assert.Equal(t, "dev", explained[1].Origin.Source)
+
+	explained, err = inventory.Explain("dev", "network.subnets[0].name")
+	require.NoError(t, err)
+	require.Len(t, explained, 1)
+	assert.Equal(t, "network", explained[0].Origin.Source)
+}
@@ -0,0 +1,94 @@
+package skipper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// requiredRegex matches required placeholders: `?{required}` or `?{required:description}`.
+// The placeholder must be the whole value, it is never part of a longer string.
+var requiredRegex = regexp.MustCompile(`^\?\{required(?::(.*))?\}$`)
+
+// requiredTag is the yaml tag of required placeholders, e.g. `subscription_id: !required "the id of the subscription"`.
+const requiredTag = "!required"
+
+// RequiredValueError is returned if a target does not set a value which is marked as required.
+type RequiredValueError struct {
+	// Path of the required value inside the Data of the target.
+	Path []interface{}
+	// Description explains which value has to be set, it is empty if the placeholder does not have one.
+	Description string
+}
+
+func (e *RequiredValueError) Error() string {
+	message := fmt.Sprintf("required value '%s' is not set", FormatPath(e.Path))
+	if e.Description != "" {
+		message += ": " + e.Description
+	}
+	return message
+}
+
+func (e *RequiredValueError) dataPath() []interface{} {
+	return e.Path
+}
+
+// findRequiredValues returns an error for every required placeholder which is still part of the data, ordered by path.
+func findRequiredValues(data Data) ([]*RequiredValueError, error) {
+	var found []interface{}
+	err := data.FindValues(func(value string, path []interface{}) (interface{}, error) {
+		match := requiredRegex.FindStringSubmatch(value)
+		if match == nil {
+			return nil, nil
+		}
+		return &RequiredValueError{Path: path, Description: match[1]}, nil
+	}, &found)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []*RequiredValueError
+	for _, value := range found {
+		if requiredErr, ok := value.(*RequiredValueError); ok {
+			missing = append(missing, requiredErr)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return pathKey(missing[i].Path) < pathKey(missing[j].Path)
+	})
+
+	return missing, nil
+}
+
+// checkRequiredValues returns the located errors of all required values which the target does not set.
+func (inv *Inventory) checkRequiredValues(target *Target, data Data) error {
+	missing, err := findRequiredValues(data)
+	if err != nil {
+		return err
+	}
+
+	var errs ErrorList
+	for _, requiredErr := range missing {
+		errs.add(inv.locateError(target, requiredErr))
+	}
+	return errs.err()
+}
+
+// applyRequiredTags converts values tagged with `!required` into required placeholders.
+// The value of the tag is the description of the placeholder.
+func applyRequiredTags(node *yaml.Node) {
+	if node.Tag == requiredTag {
+		placeholder := "?{required}"
+		if node.Kind == yaml.ScalarNode && node.Value != "" {
+			placeholder = fmt.Sprintf("?{required:%s}", node.Value)
+		}
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: placeholder, Line: node.Line, Column: node.Column}
+		return
+	}
+
+	for _, child := range node.Content {
+		applyRequiredTags(child)
+	}
+}
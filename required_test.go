@@ -0,0 +1,50 @@
+package skipper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestInventoryDataRequiredValues(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/azure.yaml": "azure:\n  subscription_id: !required \"the id of the Azure subscription\"\n  location: !required\n  tags:\n    owner: ?{required:team which owns the resources}\n",
+		"classes/app.json":   `{"app": {"name": "?{required}"}}`,
+		"targets/dev.yaml":   "target:\n  skipper:\n    use: [azure, app]\n  azure:\n    subscription_id: 1234\n    location: westeurope\n    tags:\n      owner: platform\n  app:\n    name: web\n",
+		"targets/prd.yaml":   "target:\n  skipper:\n    use: [azure, app]\n  azure:\n    location: northeurope\n",
+	})
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	subscription, err := data.GetPath("azure", "subscription_id")
+	require.NoError(t, err)
+	assert.Equal(t, 1234, subscription)
+
+	_, err = inventory.Data("prd", nil, true, false)
+	require.Error(t, err)
+
+	var errs skipper.ErrorList
+	require.True(t, errors.As(err, &errs), err.Error())
+	require.Len(t, errs, 3)
+
+	var requiredErr *skipper.RequiredValueError
+	require.True(t, errors.As(errs[0], &requiredErr))
+	assert.Equal(t, []interface{}{"app", "name"}, requiredErr.Path)
+	assert.Equal(t, "", requiredErr.Description)
+	assert.Contains(t, errs[0].Error(), "/inventory/classes/app.json: required value 'app.name' is not set")
+
+	require.True(t, errors.As(errs[1], &requiredErr))
+	assert.Equal(t, []interface{}{"azure", "subscription_id"}, requiredErr.Path)
+	assert.Equal(t, "the id of the Azure subscription", requiredErr.Description)
+	var inventoryErr *skipper.InventoryError
+	require.True(t, errors.As(errs[1], &inventoryErr))
+	assert.Equal(t, "/inventory/classes/azure.yaml", inventoryErr.File)
+	assert.Equal(t, skipper.Position{Line: 2, Column: 3}, inventoryErr.Position)
+
+	assert.Contains(t, errs[2].Error(), "required value 'azure.tags.owner' is not set: team which owns the resources")
+}
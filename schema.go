@@ -0,0 +1,333 @@
+package skipper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/spf13/afero"
+)
+
+// schemaKey is the key of the Skipper configuration which declares an inline JSON schema.
+const schemaKey string = "schema"
+
+// WithSchemaPath sets the directory which contains the JSON schemas of classes.
+// The schema of a class is named after the class, e.g. `<path>/azure.network.json` for the class `azure.network`.
+// Besides JSON, schemas can be written in any supported file format (see [SupportedExtensions]).
+func WithSchemaPath(path string) InventoryOption {
+	return func(inv *Inventory) {
+		inv.schemaPath = path
+	}
+}
+
+// SchemaError is a value which does not match a JSON schema.
+type SchemaError struct {
+	// Path of the value inside the Data of the target.
+	Path []interface{}
+	// Schema describes the class or target which declares the schema.
+	Schema string
+	// Message describes the violation.
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	path := FormatPath(e.Path)
+	if path == "" {
+		path = "."
+	}
+	return fmt.Sprintf("value at '%s' does not match the schema of %s: %s", path, e.Schema, e.Message)
+}
+
+func (e *SchemaError) dataPath() []interface{} {
+	return e.Path
+}
+
+// schema is a compiled JSON schema and the class or target which declares it.
+type schema struct {
+	compiled *jsonschema.Schema
+	// name describes the declaring class or target in errors, e.g. `class 'azure'`.
+	name string
+	// file declares the schema, errors which cannot be located otherwise point into it.
+	file *YamlFile
+	// filePath is the path inside the file which corresponds to the validated value.
+	filePath []interface{}
+}
+
+// loadSchemas compiles the JSON schemas of all classes, targets and directory defaults.
+//
+// Classes declare their schema either inline (`skipper.schema`) or as file inside the schema path (see [WithSchemaPath]).
+// Targets and directory defaults can only declare inline schemas, which validate the whole Data of the target.
+func (inv *Inventory) loadSchemas() error {
+	inv.classSchemas = make(map[string][]*schema)
+	inv.targetSchemas = make(map[*Target]*schema)
+
+	if inv.schemaPath != "" {
+		exists, err := afero.DirExists(inv.fs, inv.schemaPath)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("schema path does not exist: %s", inv.schemaPath)
+		}
+	}
+
+	var errs ErrorList
+	for _, class := range inv.classFiles {
+		name := fmt.Sprintf("class '%s'", class.Name)
+		filePath := []interface{}{class.RootKey()}
+
+		inline, err := compileInlineSchema(class.File, class.configPath(schemaKey), name, filePath)
+		if err != nil {
+			errs.add(err)
+		} else if inline != nil {
+			inv.classSchemas[class.Name] = append(inv.classSchemas[class.Name], inline)
+		}
+
+		file, err := inv.loadSchemaFile(class.Name, name, class.File, filePath)
+		if err != nil {
+			errs.add(err)
+		} else if file != nil {
+			inv.classSchemas[class.Name] = append(inv.classSchemas[class.Name], file)
+		}
+	}
+
+	for _, target := range append(append([]*Target{}, inv.targetFiles...), inv.targetDefaults...) {
+		name := fmt.Sprintf("target '%s'", target.Name)
+		inline, err := compileInlineSchema(target.File, target.configPath(schemaKey), name, []interface{}{targetKey})
+		if err != nil {
+			errs.add(err)
+		} else if inline != nil {
+			inv.targetSchemas[target] = inline
+		}
+	}
+
+	return errs.err()
+}
+
+// hasSchemas returns true if any class or target declares a schema.
+func (inv *Inventory) hasSchemas() bool {
+	return len(inv.classSchemas) > 0 || len(inv.targetSchemas) > 0
+}
+
+// compileInlineSchema compiles the schema at the given path inside the file, it returns nil if there is no schema.
+func compileInlineSchema(file *YamlFile, path []interface{}, name string, filePath []interface{}) (*schema, error) {
+	document, err := file.Data.GetPath(path...)
+	if err != nil {
+		return nil, nil
+	}
+
+	compiled, err := compileSchema(filepath.Join(file.Path, FormatPath(path)), document)
+	if err != nil {
+		return nil, newFileError(file, path, fmt.Errorf("invalid schema of %s: %w", name, err))
+	}
+
+	return &schema{compiled: compiled, name: name, file: file, filePath: filePath}, nil
+}
+
+// loadSchemaFile loads the schema file of the class with the given name from the schema path.
+// It returns nil if the schema path is not set or the class does not have a schema file.
+func (inv *Inventory) loadSchemaFile(className, name string, file *YamlFile, filePath []interface{}) (*schema, error) {
+	if inv.schemaPath == "" {
+		return nil, nil
+	}
+
+	for _, extension := range SupportedExtensions() {
+		if extension == "" {
+			continue
+		}
+		schemaFile, err := NewYamlFile(filepath.Join(inv.schemaPath, className+extension))
+		if err != nil {
+			return nil, err
+		}
+		if !schemaFile.Exists(inv.fs) {
+			continue
+		}
+
+		err = schemaFile.Load(inv.fs)
+		if err != nil {
+			return nil, err
+		}
+		compiled, err := compileSchema(schemaFile.Path, schemaFile.Data)
+		if err != nil {
+			return nil, newFileError(schemaFile, nil, fmt.Errorf("invalid schema of %s: %w", name, err))
+		}
+
+		return &schema{compiled: compiled, name: name, file: file, filePath: filePath}, nil
+	}
+
+	return nil, nil
+}
+
+// compileSchema compiles the schema document, the url identifies the schema.
+func compileSchema(url string, document interface{}) (*jsonschema.Schema, error) {
+	raw, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	err = compiler.AddResource(url, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return compiler.Compile(url)
+}
+
+// validateSchemas validates the Data of the target against the schemas of all used classes and of the target itself.
+// The schema of a class validates the class data inside the Data, the schema of a target validates the whole Data.
+// The Skipper configuration (`skipper` key) is never validated.
+//
+// Values which still contain variables, calls or secrets (e.g. `${foo}`) are not validated, because they are not resolved yet.
+// Every violation is located at the file which defines the value, or at the file which declares the schema if the value does not exist.
+func (inv *Inventory) validateSchemas(target *Target, data Data) error {
+	type validation struct {
+		schema *schema
+		path   []interface{}
+	}
+
+	var validations []validation
+	classes, err := inv.GetUsedClasses(target.Name)
+	if err != nil {
+		return err
+	}
+	for _, class := range classes {
+		segments := class.NameAsIdentifier()
+		classPath := append(segments[:len(segments)-1:len(segments)-1], class.RootKey())
+		for _, s := range inv.classSchemas[class.Name] {
+			validations = append(validations, validation{schema: s, path: classPath})
+		}
+	}
+	for _, layer := range target.Lineage() {
+		if s, exists := inv.targetSchemas[layer]; exists {
+			validations = append(validations, validation{schema: s})
+		}
+	}
+
+	var errs ErrorList
+	for _, v := range validations {
+		value, err := data.GetPath(v.path...)
+		if err != nil {
+			// the value has been removed, e.g. by `skipper.unset`
+			continue
+		}
+		instance, err := jsonValue(withoutSkipperConfig(value))
+		if err != nil {
+			return err
+		}
+
+		err = v.schema.compiled.Validate(instance)
+		if err == nil {
+			continue
+		}
+		var validationErr *jsonschema.ValidationError
+		if !errors.As(err, &validationErr) {
+			errs.add(err)
+			continue
+		}
+
+		for _, violation := range schemaViolations(validationErr) {
+			relativePath := jsonPointerPath(violation.InstanceLocation, value)
+			path := append(append([]interface{}{}, v.path...), relativePath...)
+			if isUnresolved(data, path) {
+				continue
+			}
+
+			schemaErr := &SchemaError{Path: path, Schema: v.schema.name, Message: violation.Message}
+			located := inv.locateError(target, schemaErr)
+			if _, isLocated := located.(*InventoryError); !isLocated {
+				located = newFileError(v.schema.file, append(append([]interface{}{}, v.schema.filePath...), relativePath...), schemaErr)
+			}
+			errs.add(located)
+		}
+	}
+
+	return errs.err()
+}
+
+// schemaViolations returns the innermost errors of the validation error, which describe the actual violations.
+func schemaViolations(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var violations []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		violations = append(violations, schemaViolations(cause)...)
+	}
+	return violations
+}
+
+// jsonPointerPath converts the JSON pointer (e.g. `/foo/0/bar`) into a path inside the value.
+func jsonPointerPath(pointer string, value interface{}) []interface{} {
+	var path []interface{}
+	if pointer == "" {
+		return path
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		if list, isList := value.([]interface{}); isList {
+			index, err := strconv.Atoi(token)
+			if err == nil && index >= 0 && index < len(list) {
+				path = append(path, index)
+				value = list[index]
+				continue
+			}
+		}
+
+		path = append(path, token)
+		if data, isMap := asData(value); isMap {
+			value = data[token]
+		} else {
+			value = nil
+		}
+	}
+
+	return path
+}
+
+// isUnresolved returns true if the value at the path is a string which still contains a variable, call or secret,
+// or if it is a required placeholder, which is reported by Data.
+func isUnresolved(data Data, path []interface{}) bool {
+	value, err := data.GetPath(path...)
+	if err != nil {
+		return false
+	}
+	s, isString := value.(string)
+	if !isString {
+		return false
+	}
+	return variableRegex.MatchString(s) || callRegex.MatchString(s) || secretRegex.MatchString(s) || requiredRegex.MatchString(s)
+}
+
+// withoutSkipperConfig returns the value without the Skipper configuration, if it is a map.
+func withoutSkipperConfig(value interface{}) interface{} {
+	data, isMap := asData(value)
+	if !isMap || !data.HasKey(skipperKey) {
+		return value
+	}
+
+	out := make(Data, len(data))
+	for key, v := range data {
+		if key != skipperKey {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// jsonValue converts the value into its plain JSON representation, which is required for the validation.
+func jsonValue(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
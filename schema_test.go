@@ -0,0 +1,121 @@
+package skipper_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+const networkSchema = `network:
+  skipper:
+    schema:
+      type: object
+      required: [cidr]
+      properties:
+        cidr:
+          type: string
+        subnets:
+          type: array
+          items:
+            type: integer
+`
+
+func TestInventoryInlineClassSchema(t *testing.T) {
+	// the target does not set the required value, the error points at the class which declares the schema
+	_, err := newTestInventory(t, map[string]string{
+		"classes/network.yaml": networkSchema + "  subnets: [1, 2]\n",
+		"targets/dev.yaml":     "target:\n  skipper:\n    use: [network]\n",
+	})
+	require.Error(t, err)
+
+	var schemaErr *skipper.SchemaError
+	require.True(t, errors.As(err, &schemaErr), err.Error())
+	assert.Equal(t, []interface{}{"network"}, schemaErr.Path)
+	assert.Equal(t, "class 'network'", schemaErr.Schema)
+	var inventoryErr *skipper.InventoryError
+	require.True(t, errors.As(err, &inventoryErr))
+	assert.Equal(t, "/inventory/classes/network.yaml", inventoryErr.File)
+	assert.Equal(t, skipper.Position{Line: 1, Column: 1}, inventoryErr.Position)
+
+	// the target sets an invalid value, the error points at the target
+	_, err = newTestInventory(t, map[string]string{
+		"classes/network.yaml": networkSchema,
+		"targets/dev.yaml":     "target:\n  skipper:\n    use: [network]\n  network:\n    cidr: 10.0.0.0/8\n    subnets: [1, two]\n",
+	})
+	require.Error(t, err)
+	require.True(t, errors.As(err, &schemaErr), err.Error())
+	assert.Equal(t, []interface{}{"network", "subnets", 1}, schemaErr.Path)
+	require.True(t, errors.As(err, &inventoryErr))
+	assert.Equal(t, "/inventory/targets/dev.yaml", inventoryErr.File)
+	assert.Equal(t, skipper.Position{Line: 6, Column: 18}, inventoryErr.Position)
+	assert.Contains(t, err.Error(), "value at 'network.subnets[1]' does not match the schema of class 'network'")
+
+	// values which contain variables are validated by Data once they are resolved
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/network.yaml": networkSchema + "  cidr: ${cidr}\n",
+		"targets/dev.yaml":     "target:\n  skipper:\n    use: [network]\n  cidr: 10.0.0.0/8\n",
+		"targets/prd.yaml":     "target:\n  skipper:\n    use: [network]\n  cidr: 8\n",
+	})
+	require.NoError(t, err)
+	_, err = inventory.Data("dev", nil, true, false)
+	assert.NoError(t, err)
+	_, err = inventory.Data("prd", nil, true, false)
+	require.True(t, errors.As(err, &schemaErr), err.Error())
+	assert.Equal(t, []interface{}{"network", "cidr"}, schemaErr.Path)
+}
+
+func TestInventorySchemaPath(t *testing.T) {
+	fs := newTestFs(t, map[string]string{
+		"/inventory/secrets/.gitkeep":      "",
+		"/inventory/classes/app/web.yaml":  "web:\n  replicas: three\n",
+		"/inventory/targets/dev.yaml":      "target:\n  skipper:\n    use: [app.web]\n",
+		"/schemas/app.web.json":            `{"type": "object", "properties": {"replicas": {"type": "integer"}}}`,
+		"/invalid/app.web.yaml":            "type: 12\n",
+		"/inventory/targets/_defaults.yml": "target:\n  skipper:\n    schema:\n      required: [web]\n",
+	})
+
+	_, err := skipper.NewInventory(fs, "/inventory/classes", "/inventory/targets", "/inventory/secrets", skipper.WithSchemaPath("/schemas"))
+	require.Error(t, err)
+	var schemaErr *skipper.SchemaError
+	require.True(t, errors.As(err, &schemaErr), err.Error())
+	assert.Equal(t, []interface{}{"app", "web", "replicas"}, schemaErr.Path)
+	var inventoryErr *skipper.InventoryError
+	require.True(t, errors.As(err, &inventoryErr))
+	assert.Equal(t, "/inventory/classes/app/web.yaml", inventoryErr.File)
+	assert.Equal(t, skipper.Position{Line: 2, Column: 3}, inventoryErr.Position)
+
+	_, err = skipper.NewInventory(fs, "/inventory/classes", "/inventory/targets", "/inventory/secrets", skipper.WithSchemaPath("/invalid"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/invalid/app.web.yaml: invalid schema of class 'app.web'")
+
+	_, err = skipper.NewInventory(fs, "/inventory/classes", "/inventory/targets", "/inventory/secrets", skipper.WithSchemaPath("/missing"))
+	assert.EqualError(t, err, "schema path does not exist: /missing")
+}
+
+func TestInventoryTargetSchema(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/a.yaml":         "a:\n  foo: bar\n",
+		"targets/_defaults.yaml": "target:\n  skipper:\n    schema:\n      required: [a, region]\n",
+		"targets/dev.yaml":       "target:\n  skipper:\n    use: [a]\n  region: ${region}\n",
+	})
+	require.NoError(t, err)
+
+	data, err := inventory.Data("dev", map[string]interface{}{"region": "eu"}, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, "eu", data["region"])
+
+	_, err = newTestInventory(t, map[string]string{
+		"classes/a.yaml":         "a:\n  foo: bar\n",
+		"targets/_defaults.yaml": "target:\n  skipper:\n    schema:\n      required: [a, region]\n",
+		"targets/dev.yaml":       "target:\n  skipper:\n    use: [a]\n",
+	})
+	require.Error(t, err)
+	var inventoryErr *skipper.InventoryError
+	require.True(t, errors.As(err, &inventoryErr))
+	assert.Equal(t, "/inventory/targets/_defaults.yaml", inventoryErr.File)
+	assert.Contains(t, err.Error(), "does not match the schema of target '_defaults'")
+}
@@ -58,7 +58,29 @@ func NewSecretData(data string, driver string, key string) (*SecretFileData, err
 
 // FindSecrets will leverage the `FindValues` function of [Data] to recursively search for secrets.
 // All returned values are converted to *Secret and then returned as []*Secret.
+// The drivers are fetched from the shared driver cache, see [secret.SecretDriverFactory].
 func FindOrCreateSecrets(data Data, secretFiles SecretFileList, secretPath string, fs afero.Fs) ([]*Secret, error) {
+	return findOrCreateSecrets(data, secretFiles, secretPath, fs, secret.SecretDriverFactory)
+}
+
+// FindOrCreateSecretsWithDrivers works like [FindOrCreateSecrets], but uses the given drivers instead of the shared driver cache.
+// Drivers which are not part of the map yet are created using [secret.NewDriver] and added to it.
+func FindOrCreateSecretsWithDrivers(data Data, secretFiles SecretFileList, secretPath string, fs afero.Fs, drivers map[string]secret.Driver) ([]*Secret, error) {
+	return findOrCreateSecrets(data, secretFiles, secretPath, fs, func(name string) (secret.Driver, error) {
+		name = strings.ToLower(name)
+		if driver, exists := drivers[name]; exists {
+			return driver, nil
+		}
+		driver, err := secret.NewDriver(name)
+		if err != nil {
+			return nil, err
+		}
+		drivers[name] = driver
+		return driver, nil
+	})
+}
+
+func findOrCreateSecrets(data Data, secretFiles SecretFileList, secretPath string, fs afero.Fs, getDriver func(name string) (secret.Driver, error)) ([]*Secret, error) {
 	var foundValues []interface{}
 	err := data.FindValues(secretFindValueFunc(secretFiles), &foundValues)
 	if err != nil {
@@ -76,7 +98,7 @@ func FindOrCreateSecrets(data Data, secretFiles SecretFileList, secretPath strin
 		for _, sec := range vars {
 
 			// ensure that the driver is loaded and assigned to every secret
-			driver, err := secret.SecretDriverFactory(sec.DriverName)
+			driver, err := getDriver(sec.DriverName)
 			if err != nil {
 				return nil, fmt.Errorf("cannot get secret driver '%s': %w", sec.DriverName, err)
 			}
@@ -131,7 +153,7 @@ func (s *Secret) Load(fs afero.Fs) error {
 func (secret *Secret) attemptCreate(fs afero.Fs, secretPath string) error {
 	// if the secret does not have an alternative call, it is considered invalid and we cannot continue because we require the secret file to exist
 	if secret.AlternativeCall == nil {
-		return fmt.Errorf("secret does not exist and no alternative call is specified: %s in '%s'", secret.FullName(), secret.Path())
+		return fmt.Errorf("no alternative call is specified: %w", &SecretNotFoundError{Secret: secret.FullName(), File: secret.Path(), Path: secret.Identifier})
 	}
 
 	// call the given alternative call function to get the target output
@@ -184,6 +206,11 @@ func secretFindValueFunc(secretFiles SecretFileList) FindValueFunc {
 					// in case the secret file does not (yet) exist, the secretFile will be nil
 					secretFile := secretFiles.GetSecretFile(secretRelativePath)
 
+					// every secret loads its own copy of the file, the loaded secret files are shared between targets
+					if secretFile != nil {
+						secretFile = secretFile.copy()
+					}
+
 					// if the secretFile is nil, the secret does not (yet) exist.
 					// we will need to create it further on, but store the relative path by creating an empty [SecretFile]
 					if secretFile == nil {
@@ -247,10 +274,7 @@ func (s Secret) FullName() string {
 	}
 }
 
+// Path returns the path where the secret is used, see [FormatPath].
 func (s Secret) Path() string {
-	var segments []string
-	for _, seg := range s.Identifier {
-		segments = append(segments, fmt.Sprint(seg))
-	}
-	return strings.Join(segments, ".")
+	return FormatPath(s.Identifier)
 }
@@ -3,6 +3,7 @@ package secret
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/lukasjarosch/skipper/secret/driver"
 )
@@ -20,18 +21,38 @@ type ConfigurableDriver interface {
 	Configure(config map[string]interface{}) error
 }
 
-var driverCache = map[string]Driver{}
+var (
+	driverCache   = map[string]Driver{}
+	driverCacheMu sync.Mutex
+)
 
+// SecretDriverFactory returns the driver with the given name.
+// Drivers are cached, hence every caller shares the same (configured) driver instance.
+// Use [NewDriver] if the driver must not be shared.
 func SecretDriverFactory(name string) (secretDriver Driver, err error) {
 	name = strings.ToLower(name)
 
+	driverCacheMu.Lock()
+	defer driverCacheMu.Unlock()
+
 	// return a cached version of the driver if there is one
 	if secretDriver, cached := driverCache[name]; cached {
 		return secretDriver, nil
 	}
 
 	// create new driver and cache it
-	switch name {
+	secretDriver, err = NewDriver(name)
+	if err != nil {
+		return nil, err
+	}
+	driverCache[name] = secretDriver
+
+	return secretDriver, nil
+}
+
+// NewDriver creates a new, unconfigured driver with the given name.
+func NewDriver(name string) (secretDriver Driver, err error) {
+	switch strings.ToLower(name) {
 	case "plain":
 		secretDriver, err = driver.NewPlain()
 	case "base64":
@@ -43,8 +64,9 @@ func SecretDriverFactory(name string) (secretDriver Driver, err error) {
 	default:
 		return nil, fmt.Errorf("driver '%s' cannot be loaded: not implemented", name)
 	}
-
-	driverCache[name] = secretDriver
+	if err != nil {
+		return nil, err
+	}
 
 	return secretDriver, nil
 }
@@ -6,6 +6,7 @@ import (
 	"regexp"
 
 	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
 )
 
 // skipperKey is the key used to load skipper-related configurations from YAML files
@@ -17,6 +18,9 @@ type SkipperConfig struct {
 	Copies      []CopyConfig      `yaml:"copy,omitempty"`
 	IgnoreRegex []string          `yaml:"ignore_regex,omitempty"`
 	Renames     []RenameConfig    `yaml:"rename,omitempty"`
+
+	// directives are the merge directives of the configuration keys (e.g. `use::replace`), see [MergeSkipperConfig].
+	directives map[string]mergeDirective
 }
 
 type CopyConfig struct {
@@ -43,22 +47,82 @@ func (config *SkipperConfig) IsSet() bool {
 	return config != nil
 }
 
-// MergeSkipperConfig merges a list of configs into one
+// MergeSkipperConfig merges a list of configs into one.
+// Lists are appended, unless a config sets a merge directive on the key (e.g. `use::replace` or `use: !replace [...]`).
 func MergeSkipperConfig(merge ...*SkipperConfig) (mergedConfig *SkipperConfig) {
 	mergedConfig = new(SkipperConfig)
 	for _, config := range merge {
 		if config == nil {
 			continue
 		}
-		mergedConfig.Classes = append(mergedConfig.Classes, config.Classes...)
-		mergedConfig.Components = append(mergedConfig.Components, config.Components...)
-		mergedConfig.Copies = append(mergedConfig.Copies, config.Copies...)
-		mergedConfig.IgnoreRegex = append(mergedConfig.IgnoreRegex, config.IgnoreRegex...)
-		mergedConfig.Renames = append(mergedConfig.Renames, config.Renames...)
+		mergedConfig.Classes = mergeConfigList(mergedConfig.Classes, config.Classes, config.directives[useKey])
+		mergedConfig.Components = mergeConfigList(mergedConfig.Components, config.Components, config.directives["components"])
+		mergedConfig.Copies = mergeConfigList(mergedConfig.Copies, config.Copies, config.directives["copy"])
+		mergedConfig.IgnoreRegex = mergeConfigList(mergedConfig.IgnoreRegex, config.IgnoreRegex, config.directives["ignore_regex"])
+		mergedConfig.Renames = mergeConfigList(mergedConfig.Renames, config.Renames, config.directives["rename"])
+		mergedConfig.directives = mergeConfigDirectives(mergedConfig.directives, config.directives)
 	}
 	return mergedConfig
 }
 
+// mergeConfigList merges the list of a configuration key into base, following the merge directive of the key.
+func mergeConfigList[T any](base, overlay []T, directive mergeDirective) []T {
+	switch directive.strategy {
+	case mergeReplace:
+		return append([]T(nil), overlay...)
+	case mergeDelete:
+		return nil
+	case mergePrepend:
+		return append(append([]T(nil), overlay...), base...)
+	default:
+		return append(append([]T(nil), base...), overlay...)
+	}
+}
+
+// mergeConfigDirectives returns the merge directives of a merged configuration, which is merged into other configurations later on.
+// Once a key has been replaced, the merged configuration replaces the key as well.
+func mergeConfigDirectives(base, overlay map[string]mergeDirective) map[string]mergeDirective {
+	merged := make(map[string]mergeDirective)
+	for key, directive := range base {
+		if directive.strategy == mergeReplace || directive.strategy == mergeDelete {
+			merged[key] = mergeDirective{strategy: mergeReplace}
+		}
+	}
+	for key, directive := range overlay {
+		if _, replaced := merged[key]; !replaced || directive.strategy == mergeReplace || directive.strategy == mergeDelete {
+			merged[key] = directive
+		}
+	}
+	return merged
+}
+
+// loadConfigSection unmarshals the Skipper configuration at path inside the file into config.
+// The merge directives of the configuration keys are removed from the keys and returned.
+func loadConfigSection(file *YamlFile, config interface{}, path ...interface{}) (map[string]mergeDirective, error) {
+	value, err := file.Data.GetPath(path...)
+	if err != nil {
+		return nil, err
+	}
+	section, isMap := asData(value)
+	if !isMap {
+		return nil, file.UnmarshalPath(config, path...)
+	}
+
+	directives := make(map[string]mergeDirective)
+	plain := make(Data, len(section))
+	for key, value := range section {
+		key, directive := parseMergeKey(key)
+		directives[key] = directive
+		plain[key] = value
+	}
+
+	bytes, err := yaml.Marshal(plain)
+	if err != nil {
+		return nil, err
+	}
+	return directives, yaml.Unmarshal(bytes, config)
+}
+
 // LoadSkipperConfig attempts to load a SkipperConfig from the given YamlFile with the passed rootKey
 func LoadSkipperConfig(file *YamlFile, rootKey string) (*SkipperConfig, error) {
 	if file == nil {
@@ -74,10 +138,11 @@ func LoadSkipperConfig(file *YamlFile, rootKey string) (*SkipperConfig, error) {
 	}
 
 	var config SkipperConfig
-	err := file.UnmarshalPath(&config, rootKey, skipperKey)
+	directives, err := loadConfigSection(file, &config, rootKey, skipperKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal SkipperConfig: %w", err)
 	}
+	config.directives = directives
 
 	// ensure ignore regex can be compiled
 	for _, regex := range config.IgnoreRegex {
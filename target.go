@@ -3,33 +3,62 @@ package skipper
 import (
 	"fmt"
 	"path/filepath"
-	"regexp"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 const (
-	targetKey string = "target"
-	useKey    string = "use"
+	targetKey  string = "target"
+	useKey     string = "use"
+	extendsKey string = "extends"
+	unsetKey   string = "unset"
+	secretsKey string = "secrets"
+	// defaultsFileName is the name (without extension) of the files which hold directory defaults for targets.
+	defaultsFileName string = "_defaults"
 )
 
-var wildcardUseRegex regexp.Regexp = *regexp.MustCompile(`^\w+\.\*$`)
-
 // Target defines which classes to use for the compilation.
 type Target struct {
 	File *YamlFile
 	// Name is the relative path of the file inside the inventory
 	// where '/' is replaced with '.' and without file extension.
 	Name string
-	// UsedWildcardClasses holds all resolved wildcard class imports as specified in the `targets.skipper.use` key.
+	// UsedWildcardClasses holds all class patterns and negations as specified in the `targets.skipper.use` key.
+	// They are resolved into the used classes once the target is loaded by the [Inventory].
 	UsedWildcardClasses []string
 	// Configuration is the skipper-internal configuration which needs to be present on every target.
 	Configuration TargetConfig
 	// SkipperConfig is the generic Skipper configuration which can be use throughout targets and classes
 	SkipperConfig *SkipperConfig
+	// Parent is the target which is extended by this target (`skipper.extends`).
+	// It is set once the target is loaded by the [Inventory].
+	Parent *Target
+	// Defaults are the directory defaults (`_defaults.yaml`) which apply to the target,
+	// starting with the top-most directory and ending with the directory of the target.
+	// Defaults which already apply to the parent are not repeated.
+	// They are set once the target is loaded by the [Inventory].
+	Defaults []*Target
+
+	relativePath string
+	// patches are the patch files declared by the target itself, see [TargetConfig.Patches].
+	patches []*targetPatch
 }
 
 type TargetConfig struct {
 	Secrets TargetSecretConfig `mapstructure:"secrets,omitempty"`
+	// Extends is the name of the target which is extended by this target.
+	// The target inherits the used classes, the configuration and the data of the extended target.
+	Extends string `yaml:"extends,omitempty"`
+	// Unset is a list of paths (e.g. `azure.network.subnets[0]`, see [ParsePath]) which are removed from the Data
+	// once all classes and the target are merged, but before any variables are resolved.
+	Unset []string `yaml:"unset,omitempty"`
+	// Patches is a list of JSON Patch (RFC 6902) or JSON Merge Patch (RFC 7396) files, relative to the target file.
+	// They are applied once the paths of Unset are removed, but before any variables are resolved.
+	Patches []string `yaml:"patches,omitempty"`
+
+	// directives are the merge directives of the configuration keys (e.g. `unset::replace`), see [mergeTargetConfig].
+	directives map[string]mergeDirective
 }
 
 type TargetSecretConfig struct {
@@ -44,33 +73,29 @@ func NewTarget(file *YamlFile, inventoryPath string) (*Target, error) {
 		return nil, fmt.Errorf("inventoryPath cannot be empty")
 	}
 
-	// create target name from inventory-relative path
-	fileName := strings.TrimSuffix(inventoryPath, filepath.Ext(inventoryPath))
-	name := strings.ReplaceAll(fileName, "/", ".")
-
 	// every target must have the same root key
 	if !file.Data.HasKey(targetKey) {
-		return nil, fmt.Errorf("target must have valid top-level key")
+		return nil, newFileError(file, nil, fmt.Errorf("target must have valid top-level key"))
 	}
 
 	// every target must have the 'skipper' key, which is used to load the Skipper-internal target configuration
-	var config TargetConfig
-	err := file.UnmarshalPath(&config, targetKey, skipperKey)
+	config, err := loadTargetConfig(file)
 	if err != nil {
-		return nil, fmt.Errorf("missing skipper key in target: %w", err)
+		return nil, newFileError(file, []interface{}{targetKey, skipperKey}, fmt.Errorf("missing skipper key in target: %w", err))
 	}
 
 	// attempt to load the generic skipper config
 	skipperConfig, err := LoadSkipperConfig(file, targetKey)
 	if err != nil {
-		return nil, err
+		return nil, newFileError(file, []interface{}{targetKey, skipperKey}, err)
 	}
 
 	target := &Target{
 		File:          file,
-		Name:          name,
+		Name:          targetName(inventoryPath),
 		Configuration: config,
 		SkipperConfig: skipperConfig,
+		relativePath:  inventoryPath,
 	}
 
 	err = target.loadUsedWildcardClasses()
@@ -84,30 +109,232 @@ func NewTarget(file *YamlFile, inventoryPath string) (*Target, error) {
 	return target, nil
 }
 
+// newDefaultsTarget creates a target from a directory defaults file (`_defaults.yaml`).
+// Defaults have the same structure as targets, but the 'skipper' key is optional
+// and they cannot extend other targets.
+func newDefaultsTarget(file *YamlFile, inventoryPath string) (*Target, error) {
+	if file == nil {
+		return nil, fmt.Errorf("file cannot be nil")
+	}
+
+	if !file.Data.HasKey(targetKey) {
+		return nil, newFileError(file, nil, fmt.Errorf("defaults must have valid top-level key"))
+	}
+
+	var config TargetConfig
+	if _, err := file.Data.GetPath(targetKey, skipperKey); err == nil {
+		config, err = loadTargetConfig(file)
+		if err != nil {
+			return nil, newFileError(file, []interface{}{targetKey, skipperKey}, err)
+		}
+	}
+	if config.Extends != "" {
+		return nil, newFileError(file, []interface{}{targetKey, skipperKey, extendsKey}, fmt.Errorf("defaults cannot extend targets"))
+	}
+
+	skipperConfig, err := LoadSkipperConfig(file, targetKey)
+	if err != nil {
+		return nil, newFileError(file, []interface{}{targetKey, skipperKey}, err)
+	}
+
+	return &Target{
+		File:          file,
+		Name:          targetName(inventoryPath),
+		Configuration: config,
+		SkipperConfig: skipperConfig,
+		relativePath:  inventoryPath,
+	}, nil
+}
+
+// targetName creates the target name from the inventory-relative path.
+func targetName(inventoryPath string) string {
+	fileName := strings.TrimSuffix(inventoryPath, filepath.Ext(inventoryPath))
+	return strings.ReplaceAll(fileName, "/", ".")
+}
+
+// isDefaultsFile returns true if the inventory-relative path points to a directory defaults file.
+func isDefaultsFile(inventoryPath string) bool {
+	base := filepath.Base(inventoryPath)
+	return strings.TrimSuffix(base, filepath.Ext(base)) == defaultsFileName
+}
+
+// directoryDepth returns the number of directories in the inventory-relative path of the target.
+func (t *Target) directoryDepth() int {
+	dir := filepath.Dir(t.relativePath)
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+// appliesTo returns true if the defaults target t is located in the directory (or any parent directory) of the given target.
+func (t *Target) appliesTo(target *Target) bool {
+	dir := filepath.Dir(t.relativePath)
+	if dir == "." {
+		return true
+	}
+	targetDir := filepath.Dir(target.relativePath)
+	return targetDir == dir || strings.HasPrefix(targetDir, dir+"/")
+}
+
 func (t *Target) ReloadConfiguration() {
 	// every target must have the 'skipper' key, which is used to load the Skipper-internal target configuration
-	var config TargetConfig
-	t.File.UnmarshalPath(&config, targetKey, skipperKey)
+	config, _ := loadTargetConfig(t.File)
 	t.Configuration = config
+
+	// the defaults of the nearest directory have precedence over the ones further up
+	for i := len(t.Defaults) - 1; i >= 0; i-- {
+		t.Defaults[i].ReloadConfiguration()
+		t.Configuration = mergeTargetConfig(t.Defaults[i].Configuration, t.Configuration)
+	}
+
+	if t.Parent != nil {
+		t.Parent.ReloadConfiguration()
+		t.Configuration = mergeTargetConfig(t.Parent.Configuration, t.Configuration)
+	}
+}
+
+// Lineage returns all targets from which the target inherits, starting with the top-most parent and ending with the target itself.
+// The directory defaults of every target are placed right before it.
+func (t *Target) Lineage() []*Target {
+	var lineage []*Target
+	if t.Parent != nil {
+		lineage = t.Parent.Lineage()
+	}
+	lineage = append(lineage, t.Defaults...)
+	return append(lineage, t)
+}
+
+// applyDefaults merges the configuration of the target defaults into the target, where the target has precedence.
+func (t *Target) applyDefaults() {
+	configurations := make([]*SkipperConfig, 0, len(t.Defaults)+1)
+	for _, defaults := range t.Defaults {
+		configurations = append(configurations, defaults.SkipperConfig)
+	}
+	skipperConfig := MergeSkipperConfig(append(configurations, t.SkipperConfig)...)
+	skipperConfig.Classes = uniqueStrings(skipperConfig.Classes)
+	t.SkipperConfig = skipperConfig
+
+	for i := len(t.Defaults) - 1; i >= 0; i-- {
+		t.Configuration = mergeTargetConfig(t.Defaults[i].Configuration, t.Configuration)
+	}
+}
+
+// inherit makes the target extend the given parent.
+// The configuration of the parent is merged into the target configuration, where the target has precedence.
+// The used classes of the parent are used before the classes of the target.
+func (t *Target) inherit(parent *Target) {
+	t.Parent = parent
+	t.Configuration = mergeTargetConfig(parent.Configuration, t.Configuration)
+
+	skipperConfig := MergeSkipperConfig(parent.SkipperConfig, t.SkipperConfig)
+	skipperConfig.Classes = uniqueStrings(skipperConfig.Classes)
+	t.SkipperConfig = skipperConfig
+}
+
+// loadTargetConfig loads the TargetConfig from the 'skipper' key of the target file.
+func loadTargetConfig(file *YamlFile) (TargetConfig, error) {
+	var config TargetConfig
+	directives, err := loadConfigSection(file, &config, targetKey, skipperKey)
+	config.directives = directives
+	return config, err
+}
+
+// mergeTargetConfig merges two target configurations, the override has precedence.
+// Lists are appended and the secret drivers are merged, unless the override sets a merge directive on the key.
+func mergeTargetConfig(base, override TargetConfig) TargetConfig {
+	merged := TargetConfig{
+		Extends:    override.Extends,
+		Unset:      mergeConfigList(base.Unset, override.Unset, override.directives[unsetKey]),
+		Patches:    mergeConfigList(base.Patches, override.Patches, override.directives[patchesKey]),
+		directives: mergeConfigDirectives(base.directives, override.directives),
+	}
+
+	baseDrivers := Data(base.Secrets.Drivers)
+	if strategy := override.directives[secretsKey].strategy; strategy == mergeReplace || strategy == mergeDelete {
+		baseDrivers = nil
+	}
+	drivers := mergeData(baseDrivers, Data(override.Secrets.Drivers), "", nil)
+	if len(drivers) > 0 {
+		merged.Secrets.Drivers = drivers
+	}
+
+	return merged
+}
+
+// targetConfigFromData loads the TargetConfig from the 'skipper' key of the merged Data of a target.
+// Because the Data might have been resolved already, the configuration can contain resolved variables and calls.
+func targetConfigFromData(data Data) (TargetConfig, error) {
+	var config TargetConfig
+	if !data.HasKey(skipperKey) {
+		return config, nil
+	}
+
+	bytes, err := yaml.Marshal(data[skipperKey])
+	if err != nil {
+		return config, err
+	}
+	err = yaml.Unmarshal(bytes, &config)
+	if err != nil {
+		return config, fmt.Errorf("invalid target configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// configPath returns the path of the given key of the Skipper configuration inside the target file.
+func (t *Target) configPath(key string) []interface{} {
+	return []interface{}{targetKey, skipperKey, key}
+}
+
+// lineageError creates an error which points to the item of the Skipper configuration list (e.g. `use` or `unset`)
+// inside the file of the target, its directory defaults or its parents, depending on who defines the item.
+func (t *Target) lineageError(key, item string, err error) error {
+	lineage := t.Lineage()
+	for i := len(lineage) - 1; i >= 0; i-- {
+		listPath := lineage[i].configPath(key)
+		itemPath := findListItem(lineage[i].File, listPath, item)
+		if len(itemPath) > len(listPath) {
+			return newFileError(lineage[i].File, itemPath, err)
+		}
+	}
+	return newFileError(t.File, t.configPath(key), err)
+}
+
+// containsTarget returns true if the target is part of the given list.
+func containsTarget(targets []*Target, target *Target) bool {
+	for _, t := range targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueStrings returns the given strings without duplicates, the first occurrence is kept.
+func uniqueStrings(in []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, s := range in {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
 }
 
 func (t *Target) Data() Data {
 	return t.File.Data.Get(targetKey)
 }
 
-// loadUsedWildcardClasses will extract all wildcards-uses from the configuration,
-// remove them from the loaded configuration and store them in UsedWildcardClasses for
-// further processing.
+// loadUsedWildcardClasses will extract all class patterns and negations from the configuration
+// and store them in UsedWildcardClasses.
 func (t *Target) loadUsedWildcardClasses() error {
-	// convert []interface to []string
-	for key, class := range t.SkipperConfig.Classes {
-		// load wildcard imports separately as they need to be resolved
-		if match := wildcardUseRegex.FindAllString(class, 1); len(match) == 1 {
-			wildcardUse := match[0]
-			t.UsedWildcardClasses = append(t.UsedWildcardClasses, wildcardUse)
-			// remove wildcard classes from regular classes config
-			t.SkipperConfig.Classes = append(t.SkipperConfig.Classes[:key], t.SkipperConfig.Classes[key+1:]...)
-			continue
+	for _, class := range t.SkipperConfig.Classes {
+		if IsClassPattern(class) {
+			t.UsedWildcardClasses = append(t.UsedWildcardClasses, class)
 		}
 	}
 
@@ -116,12 +343,28 @@ func (t *Target) loadUsedWildcardClasses() error {
 
 // targetYamlFileLoader returns a YamlFileLoaderFunc which is capable of
 // creating Targets from a given YamlFile.
-// The created targets are then appended to the passed targetList.
-func targetYamlFileLoader(targetList *[]*Target) YamlFileLoaderFunc {
+// The created targets are then appended to the passed targetList,
+// directory defaults are appended to the defaultsList instead.
+func targetYamlFileLoader(targetList *[]*Target, defaultsList *[]*Target) YamlFileLoaderFunc {
 	return func(file *YamlFile, relativePath string) error {
+		if isDefaultsFile(relativePath) {
+			defaults, err := newDefaultsTarget(file, relativePath)
+			if err != nil {
+				return err
+			}
+			for _, existing := range *defaultsList {
+				if filepath.Dir(existing.relativePath) == filepath.Dir(relativePath) {
+					return newFileError(file, nil, fmt.Errorf("directory already has a defaults file: %s", existing.File.Path))
+				}
+			}
+
+			(*defaultsList) = append((*defaultsList), defaults)
+			return nil
+		}
+
 		target, err := NewTarget(file, relativePath)
 		if err != nil {
-			return fmt.Errorf("%s: %w", file.Path, err)
+			return err
 		}
 
 		(*targetList) = append((*targetList), target)
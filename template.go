@@ -42,6 +42,32 @@ var customFuncs map[string]any = map[string]any{
 		return time.Now().AddDate(y, m, d).Format(time.RFC3339)
 	},
 
+	// getPath returns the value at the path inside the map, e.g. `{{ getPath "azure.subnets[0].name" .Inventory }}`, see [ParsePath].
+	"getPath": func(path string, value interface{}) (interface{}, error) {
+		data, ok := asData(value)
+		if !ok {
+			return nil, fmt.Errorf("getPath expects a map, got %T", value)
+		}
+		return data.Lookup(path)
+	},
+
+	// getAll returns all values which match the path inside the map, e.g. `{{ getAll "azure.subnets[*].name" .Inventory }}`.
+	"getAll": func(path string, value interface{}) ([]interface{}, error) {
+		data, ok := asData(value)
+		if !ok {
+			return nil, fmt.Errorf("getAll expects a map, got %T", value)
+		}
+		matches, err := data.LookupAll(path)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(matches))
+		for i, match := range matches {
+			values[i] = match.Value
+		}
+		return values, nil
+	},
+
 	"context": func(values ...interface{}) (map[string]interface{}, error) {
 		if len(values)%2 != 0 {
 			return nil, fmt.Errorf("uneven amount of values")
@@ -68,6 +94,7 @@ type Templater struct {
 	templateFs       afero.Fs
 	outputFs         afero.Fs
 	templateFuncs    template.FuncMap
+	writtenFiles     []string
 }
 
 func NewTemplater(fileSystem afero.Fs, templateRootPath, outputRootPath string, userFuncMap map[string]any, ignoreRegex []string) (*Templater, error) {
@@ -206,6 +233,7 @@ func (t *Templater) execute(tplFile *File, data any, targetPath string, allowNoV
 			if err != nil {
 				return fmt.Errorf("could not copy file %s: %w", tplFile.Path, err)
 			}
+			t.writtenFiles = append(t.writtenFiles, filepath.Clean(targetPath))
 			return nil
 		}
 	}
@@ -256,10 +284,17 @@ func (t *Templater) execute(tplFile *File, data any, targetPath string, allowNoV
 	if err != nil {
 		return err
 	}
+	t.writtenFiles = append(t.writtenFiles, filepath.Clean(targetPath))
 
 	return nil
 }
 
+// WrittenFiles returns the paths of all files which have been written by the templater so far.
+// The paths are relative to the output root path.
+func (t *Templater) WrittenFiles() []string {
+	return t.writtenFiles
+}
+
 // ExecuteComponents will only execute the templates as they are defined in the given components.
 func (t *Templater) ExecuteComponents(data any, components []ComponentConfig, allowNoValue bool) error {
 	if len(components) == 0 {
@@ -0,0 +1,135 @@
+package skipper
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// useNegationPrefix marks a `use` entry which excludes classes from the pattern matches.
+	useNegationPrefix = "!"
+	// recursiveWildcard is the pattern segment which matches any number of class name segments.
+	recursiveWildcard = "**"
+)
+
+// IsClassPattern returns true if the given `use` entry is not a plain class name,
+// but a pattern or a negation which needs to be resolved against the existing classes.
+func IsClassPattern(use string) bool {
+	return strings.HasPrefix(use, useNegationPrefix) || strings.ContainsAny(use, "*?[")
+}
+
+// MatchClassPattern reports whether the class name matches the given pattern.
+// The pattern is matched segment-wise, the segments of patterns and class names are separated by '.'.
+//
+//   - `*` matches any sequence of characters within a single segment (`foo.*` matches `foo.bar` but not `foo.bar.baz`)
+//   - `?` matches any single character within a segment
+//   - `[...]` matches a character class within a segment, see [path.Match] for the syntax
+//   - `**` as whole segment matches any number of segments (`foo.**.db` matches `foo.db` and `foo.bar.baz.db`).
+//     As last segment it matches at least one segment, hence `foo.**` matches everything below `foo`, but not `foo` itself.
+func MatchClassPattern(pattern, className string) (bool, error) {
+	return matchClassSegments(strings.Split(pattern, "."), strings.Split(className, "."))
+}
+
+func matchClassSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == recursiveWildcard {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return len(name) > 0, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				matched, err := matchClassSegments(rest, name[i:])
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+		matched, err := path.Match(pattern[0], name[0])
+		if err != nil {
+			return false, fmt.Errorf("invalid class pattern segment '%s': %w", pattern[0], err)
+		}
+		if !matched {
+			return false, nil
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+// resolveClassUses resolves a list of `use` entries against the given class names.
+//
+// Plain class names are kept as they are, checking whether they exist is up to the caller.
+// A pattern is replaced with all matching class names in lexical order.
+// Negations (`!foo.legacy` or `!foo.*.legacy`) exclude classes from all pattern matches of the list,
+// but they do not remove classes which are listed explicitly.
+// Every class is only contained once in the result, at the position where it is used first.
+func resolveClassUses(uses []string, classNames []string) ([]string, error) {
+	var negations []string
+	for _, use := range uses {
+		if strings.HasPrefix(use, useNegationPrefix) {
+			negations = append(negations, strings.TrimPrefix(use, useNegationPrefix))
+		}
+	}
+
+	isNegated := func(className string) (bool, error) {
+		for _, negation := range negations {
+			matched, err := MatchClassPattern(negation, className)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+	}
+
+	sortedClassNames := append([]string{}, classNames...)
+	sort.Strings(sortedClassNames)
+
+	var resolved []string
+	seen := make(map[string]bool)
+	add := func(className string) {
+		if seen[className] {
+			return
+		}
+		seen[className] = true
+		resolved = append(resolved, className)
+	}
+
+	for _, use := range uses {
+		if strings.HasPrefix(use, useNegationPrefix) {
+			continue
+		}
+		if !IsClassPattern(use) {
+			add(use)
+			continue
+		}
+
+		for _, className := range sortedClassNames {
+			matched, err := MatchClassPattern(use, className)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+
+			negated, err := isNegated(className)
+			if err != nil {
+				return nil, err
+			}
+			if negated {
+				continue
+			}
+			add(className)
+		}
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,71 @@
+package skipper_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestMatchClassPattern(t *testing.T) {
+	table := []struct {
+		Pattern   string
+		ClassName string
+		Expected  bool
+	}{
+		{Pattern: "foo.*", ClassName: "foo.bar", Expected: true},
+		{Pattern: "foo.*", ClassName: "foo", Expected: false},
+		{Pattern: "foo.*", ClassName: "foo.bar.baz", Expected: false},
+		{Pattern: "foo.bar*", ClassName: "foo.barbaz", Expected: true},
+		{Pattern: "foo.bar.*", ClassName: "foo.barbaz", Expected: false},
+		{Pattern: "foo.**", ClassName: "foo", Expected: false},
+		{Pattern: "foo.**", ClassName: "foo.bar", Expected: true},
+		{Pattern: "foo.**", ClassName: "foo.bar.baz", Expected: true},
+		{Pattern: "foo.*.db", ClassName: "foo.west.db", Expected: true},
+		{Pattern: "foo.*.db", ClassName: "foo.west.cache", Expected: false},
+		{Pattern: "foo.**.db", ClassName: "foo.db", Expected: true},
+		{Pattern: "foo.**.db", ClassName: "foo.a.b.db", Expected: true},
+		{Pattern: "**.db", ClassName: "foo.db", Expected: true},
+		{Pattern: "foo.?b", ClassName: "foo.db", Expected: true},
+		{Pattern: "foo.[a-c]b", ClassName: "foo.db", Expected: false},
+		{Pattern: "foo", ClassName: "foo", Expected: true},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.Pattern+"/"+tt.ClassName, func(t *testing.T) {
+			matched, err := skipper.MatchClassPattern(tt.Pattern, tt.ClassName)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Expected, matched)
+		})
+	}
+
+	_, err := skipper.MatchClassPattern("foo.[", "foo.bar")
+	assert.Error(t, err)
+}
+
+func TestInventoryClassPatterns(t *testing.T) {
+	inventory, err := newTestInventory(t, map[string]string{
+		"classes/common.yaml":         "common:\n  foo: bar\n",
+		"classes/foo/bar.yaml":        "bar:\n  a: b\n",
+		"classes/foo/barbaz.yaml":     "barbaz:\n  a: b\n",
+		"classes/foo/legacy.yaml":     "legacy:\n  a: b\n",
+		"classes/foo/west/db.yaml":    "db:\n  a: b\n",
+		"classes/foo/west/cache.yaml": "cache:\n  a: b\n",
+		"classes/foo/east/db.yaml":    "db:\n  a: b\n",
+		"classes/services/api.yaml":   "api:\n  skipper:\n    use: [\"foo.*.db\", \"!foo.west.*\"]\n",
+		"targets/dev.yaml":            "target:\n  skipper:\n    use: [foo.**, '!foo.legacy', '!foo.*.cache', common, foo.bar]\n",
+		"targets/prod.yaml":           "target:\n  skipper:\n    use: [services.api, foo.legacy, '!foo.legacy', foo.bar*]\n",
+	})
+	require.NoError(t, err)
+
+	classes, err := inventory.GetUsedClasses("dev")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo.bar", "foo.barbaz", "foo.east.db", "foo.west.db", "common"}, classNames(classes))
+	assert.Equal(t, []string{"foo.**", "!foo.legacy", "!foo.*.cache"}, inventory.GetTarget("dev").UsedWildcardClasses)
+
+	classes, err = inventory.GetUsedClasses("prod")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo.east.db", "services.api", "foo.legacy", "foo.bar", "foo.barbaz"}, classNames(classes))
+}
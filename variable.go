@@ -1,14 +1,15 @@
 package skipper
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 )
 
-// valid variables: ${foo:bar} ${foo:bar:baz} ${something}
+// valid variables: ${foo:bar} ${foo:bar:baz} ${something} ${foo:list[0]} ${foo:list[-1]} ${foo:"a.b"}
 // invalid variables: ${foo:} ${bar::} ${:bar}
-var variableRegex = regexp.MustCompile(`\$\{((\w*)(\:\w+)*)\}`)
+var variableRegex = regexp.MustCompile(`\$\{(\w*(?:\:(?:\w+|"[^"{}]*")|\[(?:-?\d+|"[^"{}]*")\])*)\}`)
 
 // Variable is a keyword which self-references the Data map it is defined in.
 // A Variable has the form ${key:key}.
@@ -16,6 +17,7 @@ type Variable struct {
 	// Name of the variable is whatever string is between ${}.
 	// + For dynamic variables, this can be a ':' separated string which points somewhere into the Data map.
 	// 	 The reason we use ':' is to improve readability between curly braces.
+	// 	 Besides the separator, the path grammar of [ParsePath] applies, e.g. `${foo:list[-1]}` or `${foo:"a.b"}`.
 	// + For predefined variables, this can be any string and must not be a path into the Data map.
 	Name string
 	// Identifier is the list of keys which point to the variable itself within the data set in which it is used.
@@ -26,20 +28,18 @@ func (v Variable) FullName() string {
 	return fmt.Sprintf("${%s}", v.Name)
 }
 
+// Path returns the path where the variable is used, see [FormatPath].
 func (v Variable) Path() string {
-	var segments []string
-	for _, seg := range v.Identifier {
-		segments = append(segments, fmt.Sprint(seg))
-	}
-	return strings.Join(segments, ".")
+	return FormatPath(v.Identifier)
 }
 
+// NameAsIdentifier returns the path the variable points to.
+// The name is parsed like a path (see [ParsePath]), except that the keys are separated by ':'.
 func (v Variable) NameAsIdentifier() (id []interface{}) {
-	tmp := strings.Split(v.Name, ":")
-	id = make([]interface{}, len(tmp))
-
-	for i := 0; i < len(tmp); i++ {
-		id[i] = tmp[i]
+	id, err := parsePath(v.Name, ":")
+	if err != nil || len(id) == 0 {
+		// the name cannot point into the Data, the variable is not defined
+		return []interface{}{v.Name}
 	}
 	return id
 }
@@ -71,7 +71,16 @@ func FindVariables(data Data) ([]Variable, error) {
 // ReplaceVariables searches and replaces variables defined in data.
 // The classFiles are used for local referencing variables (class internal references).
 // predefinedVariables can be used to provide global user-defined variables.
+//
+// Variables which do not point to any value are ignored and kept as they are,
+// because they might be meant for other tools (e.g. `${HOME}` inside a shell script).
 func ReplaceVariables(data Data, classFiles []*Class, predefinedVariables map[string]interface{}) (err error) {
+	_, err = replaceVariables(data, classFiles, predefinedVariables)
+	return err
+}
+
+// replaceVariables implements [ReplaceVariables] and returns all variables which were ignored.
+func replaceVariables(data Data, classFiles []*Class, predefinedVariables map[string]interface{}) (ignoredVariables []Variable, err error) {
 	isPredefinedVariable := func(variable Variable) bool {
 		for name := range predefinedVariables {
 			if strings.EqualFold(variable.Name, name) {
@@ -81,9 +90,6 @@ func ReplaceVariables(data Data, classFiles []*Class, predefinedVariables map[st
 		return false
 	}
 
-	// variables can be ignored, they are stored here :)
-	ignoredVariables := []Variable{}
-
 	// TODO: gosh, make this a standalone function already
 	replaceVariable := func(variable Variable) error {
 		var targetValue interface{}
@@ -96,8 +102,9 @@ func ReplaceVariables(data Data, classFiles []*Class, predefinedVariables map[st
 			if err != nil {
 
 				// for any other error than a 'key not found' there is nothing we can do
-				if !strings.Contains(err.Error(), "key not found") {
-					return fmt.Errorf("reference to invalid variable '%s': %w", variable.FullName(), err)
+				var keyErr *KeyNotFoundError
+				if !errors.As(err, &keyErr) {
+					return &UndefinedVariableError{Variable: variable.FullName(), Path: variable.Identifier, Err: err}
 				}
 
 				// Local variable handling
@@ -114,7 +121,7 @@ func ReplaceVariables(data Data, classFiles []*Class, predefinedVariables map[st
 
 					// edge case: the class root key is 'foo', and the variable used references it like ${foo:bar:baz}
 					// this would result in the full path being 'foo foo bar baz', hence we need to strip the class name from the variable reference.
-					if strings.EqualFold(class.RootKey(), variable.NameAsIdentifier()[0].(string)) {
+					if strings.EqualFold(class.RootKey(), fmt.Sprint(variable.NameAsIdentifier()[0])) {
 						fullPath = append(fullPath, variable.NameAsIdentifier()[1:]...)
 					} else {
 						// default case: the class root key is not used in the variable, we can add the full variable identifier
@@ -126,13 +133,13 @@ func ReplaceVariables(data Data, classFiles []*Class, predefinedVariables map[st
 					// as long as not all classes have been checked, we cannot be sure that the variable is undefined (aka. key not found error)
 					if targetValue == nil &&
 						i < len(classFiles) &&
-						strings.Contains(err.Error(), "key not found") {
+						errors.As(err, &keyErr) {
 						continue
 					}
 
 					// the local variable is really not defined at this point
 					if err != nil {
-						return fmt.Errorf("reference to invalid variable '%s': %w", variable.FullName(), err)
+						return &UndefinedVariableError{Variable: variable.FullName(), Path: variable.Identifier, Err: err}
 					}
 
 					break
@@ -182,7 +189,8 @@ func ReplaceVariables(data Data, classFiles []*Class, predefinedVariables map[st
 		if isInlineVariable() {
 			sourceValue = strings.ReplaceAll(fmt.Sprint(sourceValue), variable.FullName(), fmt.Sprint(targetValue))
 		} else {
-			sourceValue = targetValue
+			// the value is copied, otherwise both paths would share the same map or list
+			sourceValue = copyValue(targetValue)
 		}
 
 		// replace variable in Data
@@ -195,7 +203,7 @@ func ReplaceVariables(data Data, classFiles []*Class, predefinedVariables map[st
 	for {
 		variables, err = FindVariables(data)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// remove the ignored variables from the found variables
@@ -216,12 +224,12 @@ func ReplaceVariables(data Data, classFiles []*Class, predefinedVariables map[st
 		for _, variable := range variables {
 			err = replaceVariable(variable)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	return nil
+	return ignoredVariables, nil
 }
 
 // variableFindValueFunc implements the [FindValueFunc] and searches for variables inside [Data].
@@ -0,0 +1,372 @@
+package skipper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// VendorLockFileName is the name of the lock file which pins the vendored sources, it is located next to the project file.
+const VendorLockFileName = "skipper.lock"
+
+// VendorLockVersion is the latest version of the lock file format.
+const VendorLockVersion = 1
+
+// commitRegex matches the full SHA-1 or SHA-256 of a commit, as written into the lock file.
+var commitRegex = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// VendorSource is an external source of classes which lives in a git repository.
+type VendorSource struct {
+	// Name of the source, the source is vendored into `<vendor path>/<name>`.
+	Name string `yaml:"name"`
+	// URL of the git repository, anything `git clone` understands (e.g. `file:///repos/classes.git` or a local path).
+	URL string `yaml:"url"`
+	// Ref is the branch, tag or commit to vendor. Defaults to the HEAD of the repository.
+	Ref string `yaml:"ref,omitempty"`
+	// Path is the directory inside the repository which contains the classes. Defaults to the repository root.
+	Path string `yaml:"path,omitempty"`
+}
+
+// LockedVendorSource is a VendorSource which is pinned to a commit.
+type LockedVendorSource struct {
+	VendorSource `yaml:",inline"`
+	// Commit is the SHA of the vendored commit.
+	Commit string `yaml:"commit"`
+}
+
+// VendorLock is the content of the lock file, it pins every vendored source to a commit.
+type VendorLock struct {
+	Version int                  `yaml:"version"`
+	Sources []LockedVendorSource `yaml:"sources"`
+}
+
+// Get returns the locked source with the given name, or nil if the source is not locked.
+func (l *VendorLock) Get(name string) *LockedVendorSource {
+	for i := range l.Sources {
+		if l.Sources[i].Name == name {
+			return &l.Sources[i]
+		}
+	}
+	return nil
+}
+
+// validateVendorSources ensures that every source has a unique name and an url.
+// Neither the url nor the ref may look like an option of git and the path must stay inside the repository.
+func validateVendorSources(sources []VendorSource) error {
+	names := make(map[string]bool)
+	for i, source := range sources {
+		if err := validateVendorSourceName(source.Name); err != nil {
+			return fmt.Errorf("vendor source %d: %w", i, err)
+		}
+		if names[source.Name] {
+			return fmt.Errorf("vendor source '%s' is defined more than once", source.Name)
+		}
+		names[source.Name] = true
+
+		if source.URL == "" {
+			return fmt.Errorf("vendor source '%s': url cannot be empty", source.Name)
+		}
+		if strings.HasPrefix(source.URL, "-") {
+			return fmt.Errorf("vendor source '%s': url cannot start with '-'", source.Name)
+		}
+		if strings.HasPrefix(source.Ref, "-") {
+			return fmt.Errorf("vendor source '%s': ref cannot start with '-'", source.Name)
+		}
+		if !isRelativeSubPath(source.Path) {
+			return fmt.Errorf("vendor source '%s': path must be relative and must not contain '..'", source.Name)
+		}
+	}
+	return nil
+}
+
+// validateVendorSourceName ensures that the name can be used as directory inside the vendor path.
+func validateVendorSourceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("name '%s' must not be a path", name)
+	}
+	return nil
+}
+
+// isRelativeSubPath returns true if the slash-separated path is relative and never leaves its root.
+func isRelativeSubPath(p string) bool {
+	if path.IsAbs(p) || filepath.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return false
+	}
+	for _, segment := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadVendorLock loads the lock file of the project.
+// If no lock file exists, an empty lock is returned.
+func (p *Project) LoadVendorLock() (*VendorLock, error) {
+	lock := &VendorLock{Version: VendorLockVersion}
+
+	exists, err := afero.Exists(p.fs, p.VendorLockPath())
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return lock, nil
+	}
+
+	data, err := afero.ReadFile(p.fs, p.VendorLockPath())
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, lock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lock file %s: %w", p.VendorLockPath(), err)
+	}
+	if lock.Version > VendorLockVersion {
+		return nil, fmt.Errorf("invalid lock file %s: version %d is not supported, the latest supported version is %d", p.VendorLockPath(), lock.Version, VendorLockVersion)
+	}
+	for _, locked := range lock.Sources {
+		if err := validateVendorSourceName(locked.Name); err != nil {
+			return nil, fmt.Errorf("invalid lock file %s: %w", p.VendorLockPath(), err)
+		}
+		if !commitRegex.MatchString(locked.Commit) {
+			return nil, fmt.Errorf("invalid lock file %s: commit of source '%s' is not a commit SHA: %s", p.VendorLockPath(), locked.Name, locked.Commit)
+		}
+	}
+
+	return lock, nil
+}
+
+// checkVendor ensures that every vendor source is locked as configured and that it has been vendored.
+// This guarantees that the inventory is always loaded from the pinned commits.
+func (p *Project) checkVendor() error {
+	if len(p.Vendor.Sources) == 0 {
+		return nil
+	}
+	if err := validateVendorSources(p.Vendor.Sources); err != nil {
+		return err
+	}
+
+	lock, err := p.LoadVendorLock()
+	if err != nil {
+		return err
+	}
+
+	for _, source := range p.Vendor.Sources {
+		locked := lock.Get(source.Name)
+		if locked == nil || locked.VendorSource != source {
+			return fmt.Errorf("vendor source '%s' is not locked as configured, run 'skipper vendor'", source.Name)
+		}
+
+		exists, err := afero.DirExists(p.fs, p.VendorSourcePath(source.Name))
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("vendor source '%s' is not vendored, run 'skipper vendor'", source.Name)
+		}
+	}
+
+	return nil
+}
+
+// FetchVendor fetches all vendor sources of the project into the vendor path and writes the lock file.
+//
+// Sources which are already locked are vendored at the locked commit, unless update is true or the source configuration changed.
+// In that case the ref is resolved again and the new commit is locked.
+// Vendored sources which are no longer configured are removed.
+//
+// Vendoring requires the `git` binary.
+func (p *Project) FetchVendor(ctx context.Context, update bool) (*VendorLock, error) {
+	if err := validateVendorSources(p.Vendor.Sources); err != nil {
+		return nil, err
+	}
+
+	previous, err := p.LoadVendorLock()
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &VendorLock{Version: VendorLockVersion}
+	for _, source := range p.Vendor.Sources {
+		commit := ""
+		if locked := previous.Get(source.Name); locked != nil && locked.VendorSource == source && !update {
+			commit = locked.Commit
+		}
+
+		commit, err = p.vendorSource(ctx, source, commit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to vendor source '%s': %w", source.Name, err)
+		}
+		lock.Sources = append(lock.Sources, LockedVendorSource{VendorSource: source, Commit: commit})
+	}
+
+	// remove everything which is not vendored anymore
+	for _, locked := range previous.Sources {
+		if lock.Get(locked.Name) == nil {
+			err = p.removeVendorSource(locked.Name)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	out, err := yaml.Marshal(lock)
+	if err != nil {
+		return nil, err
+	}
+	err = afero.WriteFile(p.fs, p.VendorLockPath(), out, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// removeVendorSource removes the vendored source, which must be a directory strictly inside the vendor path.
+func (p *Project) removeVendorSource(name string) error {
+	vendorPath := filepath.Clean(p.VendorPath())
+	sourcePath := filepath.Clean(p.VendorSourcePath(name))
+	relativePath, err := filepath.Rel(vendorPath, sourcePath)
+	if err != nil || relativePath == "." || !isRelativeSubPath(filepath.ToSlash(relativePath)) {
+		return fmt.Errorf("vendor source '%s' is not inside the vendor path %s", name, vendorPath)
+	}
+	return p.fs.RemoveAll(sourcePath)
+}
+
+// vendorSource clones the source, checks out the given commit (or the resolved ref if commit is empty)
+// and copies the configured path into the vendor directory. The vendored commit is returned.
+func (p *Project) vendorSource(ctx context.Context, source VendorSource, commit string) (string, error) {
+	checkout, err := os.MkdirTemp("", "skipper-vendor-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(checkout)
+
+	_, err = runGit(ctx, "", "clone", "--quiet", "--no-checkout", "--", source.URL, checkout)
+	if err != nil {
+		return "", err
+	}
+
+	if commit == "" {
+		commit, err = resolveGitRef(ctx, checkout, source.Ref)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	_, err = runGit(ctx, checkout, "-c", "advice.detachedHead=false", "checkout", "--quiet", commit, "--")
+	if err != nil {
+		return "", err
+	}
+
+	// the path may be a symlink inside the repository, but it must not point outside of the checkout
+	sourcePath, err := filepath.EvalSymlinks(filepath.Join(checkout, filepath.FromSlash(source.Path)))
+	if err != nil {
+		return "", fmt.Errorf("path '%s' does not exist in commit %s", source.Path, commit)
+	}
+	root, err := filepath.EvalSymlinks(checkout)
+	if err != nil {
+		return "", err
+	}
+	if relativePath, err := filepath.Rel(root, sourcePath); err != nil || !isRelativeSubPath(filepath.ToSlash(relativePath)) {
+		return "", fmt.Errorf("path '%s' points outside of the repository", source.Path)
+	}
+	if info, err := os.Stat(sourcePath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("path '%s' is not a directory in commit %s", source.Path, commit)
+	}
+
+	targetPath := p.VendorSourcePath(source.Name)
+	err = p.removeVendorSource(source.Name)
+	if err != nil {
+		return "", err
+	}
+	err = copyDirToFs(sourcePath, p.fs, targetPath)
+	if err != nil {
+		return "", err
+	}
+
+	return commit, nil
+}
+
+// resolveGitRef resolves a branch, tag or commit to the commit SHA.
+// Branches only exist as remote branches after cloning, hence they are tried as well.
+func resolveGitRef(ctx context.Context, repository, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	candidates := []string{ref, "origin/" + ref}
+	for _, candidate := range candidates {
+		commit, err := runGit(ctx, repository, "rev-parse", "--verify", "--quiet", candidate+"^{commit}")
+		if err == nil {
+			return commit, nil
+		}
+	}
+
+	return "", fmt.Errorf("ref '%s' does not exist", ref)
+}
+
+// runGit runs git with the given arguments inside dir and returns the trimmed output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// copyDirToFs copies the directory at sourcePath from the operating system filesystem into targetPath of targetFs.
+// The '.git' directory and symlinks are skipped, symlinks could otherwise pull in any file of the host.
+func copyDirToFs(sourcePath string, targetFs afero.Fs, targetPath string) error {
+	return filepath.WalkDir(sourcePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(sourcePath, path)
+		if err != nil {
+			return err
+		}
+		destination := filepath.Join(targetPath, relativePath)
+
+		if entry.IsDir() {
+			return targetFs.MkdirAll(destination, 0755)
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return afero.WriteFile(targetFs, destination, data, info.Mode().Perm())
+	})
+}
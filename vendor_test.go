@@ -0,0 +1,193 @@
+package skipper_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+// git runs git inside dir and returns the trimmed output.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// commitFile writes the file into the repository and commits it.
+func commitFile(t *testing.T, repository, path, content string) string {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repository, path)), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repository, path), []byte(content), 0644))
+	git(t, repository, "add", "-A")
+	git(t, repository, "commit", "--quiet", "-m", "update "+path)
+	return git(t, repository, "rev-parse", "HEAD")
+}
+
+func TestProjectFetchVendor(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repository := t.TempDir()
+	git(t, repository, "init", "--quiet", "--initial-branch=main")
+	tagged := commitFile(t, repository, "classes/network.yaml", "network:\n  cidr: 10.0.0.0/8\n")
+	git(t, repository, "tag", "v1")
+	latest := commitFile(t, repository, "classes/network.yaml", "network:\n  cidr: 192.168.0.0/16\n")
+
+	root := t.TempDir()
+	fs := afero.NewOsFs()
+	files := map[string]string{
+		"skipper.yaml": `
+version: 1
+vendor:
+  sources:
+    - name: shared
+      url: file://` + repository + `
+      ref: v1
+      path: classes
+`,
+		"inventory/targets/dev.yaml": "target:\n  skipper:\n    use: [network]\n",
+		"inventory/secrets/.gitkeep": "",
+		"inventory/classes/.gitkeep": "",
+	}
+	for path, content := range files {
+		require.NoError(t, skipper.WriteFile(fs, filepath.Join(root, path), []byte(content), 0644))
+	}
+
+	project, err := skipper.LoadProject(fs, root)
+	require.NoError(t, err)
+
+	// the inventory cannot be loaded before the sources are vendored
+	_, err = project.NewInventory()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "skipper vendor")
+
+	lock, err := project.FetchVendor(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, lock.Sources, 1)
+	assert.Equal(t, tagged, lock.Sources[0].Commit)
+
+	inventory, err := project.NewInventory()
+	require.NoError(t, err)
+	assert.Equal(t, "vendor/shared", inventory.GetClass("network").Root)
+
+	data, err := inventory.Data("dev", nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, skipper.Data{"cidr": "10.0.0.0/8"}, data["network"])
+
+	// the locked commit is used, even if the ref moves
+	git(t, repository, "tag", "--force", "v1", latest)
+	lock, err = project.FetchVendor(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, tagged, lock.Sources[0].Commit)
+
+	lock, err = project.FetchVendor(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, latest, lock.Sources[0].Commit)
+
+	out, err := afero.ReadFile(fs, filepath.Join(root, "vendor", "shared", "network.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "192.168.0.0/16")
+
+	// a changed source configuration is not locked anymore
+	project.Vendor.Sources[0].Ref = "main"
+	_, err = project.NewInventory()
+	assert.Error(t, err)
+}
+
+func TestProjectFetchVendorInvalidSources(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{name: "option url", source: "url: --upload-pack=evil", err: "vendor source 'shared': url cannot start with '-'"},
+		{name: "option ref", source: "url: file:///repo\n      ref: --output=x", err: "vendor source 'shared': ref cannot start with '-'"},
+		{name: "absolute path", source: "url: file:///repo\n      path: /etc", err: "vendor source 'shared': path must be relative and must not contain '..'"},
+		{name: "parent path", source: "url: file:///repo\n      path: classes/../../etc", err: "vendor source 'shared': path must be relative and must not contain '..'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			project := "version: 1\nvendor:\n  sources:\n    - name: shared\n      " + tt.source + "\n"
+			require.NoError(t, afero.WriteFile(fs, "/project/skipper.yaml", []byte(project), 0644))
+
+			loaded, err := skipper.LoadProject(fs, "/project")
+			require.NoError(t, err)
+			_, err = loaded.FetchVendor(context.Background(), false)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/project/skipper.yaml", []byte("version: 1\nvendor:\n  sources:\n    - name: shared\n      url: file:///repo\n"), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/project/skipper.lock", []byte("version: 1\nsources:\n  - name: shared\n    url: file:///repo\n    commit: --orphan\n"), 0644))
+	project, err := skipper.LoadProject(fs, "/project")
+	require.NoError(t, err)
+	_, err = project.FetchVendor(context.Background(), false)
+	assert.EqualError(t, err, "invalid lock file /project/skipper.lock: commit of source 'shared' is not a commit SHA: --orphan")
+
+	// stale lock entries are removed from the vendor path, hence their names must not leave it
+	for _, name := range []string{"..", "nested/../../.."} {
+		lock := "version: 1\nsources:\n  - name: " + name + "\n    url: file:///repo\n    commit: " + strings.Repeat("a", 40) + "\n"
+		require.NoError(t, afero.WriteFile(fs, "/project/skipper.lock", []byte(lock), 0644))
+		require.NoError(t, afero.WriteFile(fs, "/project/important.txt", []byte("keep"), 0644))
+
+		_, err = project.FetchVendor(context.Background(), false)
+		assert.EqualError(t, err, "invalid lock file /project/skipper.lock: name '"+name+"' must not be a path")
+		exists, err := afero.Exists(fs, "/project/important.txt")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	}
+}
+
+func TestProjectFetchVendorSkipsSymlinks(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	secret := filepath.Join(t.TempDir(), "id_rsa")
+	require.NoError(t, os.WriteFile(secret, []byte("private key"), 0600))
+
+	repository := t.TempDir()
+	git(t, repository, "init", "--quiet", "--initial-branch=main")
+	require.NoError(t, os.MkdirAll(filepath.Join(repository, "classes"), 0755))
+	require.NoError(t, os.Symlink(secret, filepath.Join(repository, "classes", "key.yaml")))
+	commitFile(t, repository, "classes/network.yaml", "network:\n  cidr: 10.0.0.0/8\n")
+
+	root := t.TempDir()
+	fs := afero.NewOsFs()
+	project := "version: 1\nvendor:\n  sources:\n    - name: shared\n      url: file://" + repository + "\n      path: classes\n"
+	require.NoError(t, skipper.WriteFile(fs, filepath.Join(root, "skipper.yaml"), []byte(project), 0644))
+
+	loaded, err := skipper.LoadProject(fs, root)
+	require.NoError(t, err)
+	_, err = loaded.FetchVendor(context.Background(), false)
+	require.NoError(t, err)
+
+	exists, err := afero.Exists(fs, filepath.Join(root, "vendor", "shared", "network.yaml"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = afero.Exists(fs, filepath.Join(root, "vendor", "shared", "key.yaml"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
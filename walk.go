@@ -0,0 +1,128 @@
+package skipper
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SkipSubtree can be returned by a [WalkFunc] to skip the children of the current map or list.
+// It is not returned by [Data.Walk] itself.
+var SkipSubtree = fmt.Errorf("skip subtree")
+
+// WalkNode is a single value visited by [Data.Walk].
+type WalkNode struct {
+	// Path of the value inside the Data. List indices refer to the lists as they were before the walk.
+	Path []interface{}
+	// Value with its actual type, it is either a map, a list, a scalar or nil.
+	Value interface{}
+	// Parent is the map or list which contains the value.
+	Parent interface{}
+
+	replacement interface{}
+	replaced    bool
+	deleted     bool
+}
+
+// Key returns the last segment of the path, which is the key inside the parent map or the index inside the parent list.
+func (n *WalkNode) Key() interface{} {
+	return n.Path[len(n.Path)-1]
+}
+
+// Replace replaces the value inside its parent once the [WalkFunc] returns.
+// The replacement is not walked.
+func (n *WalkNode) Replace(value interface{}) {
+	n.replacement = value
+	n.replaced = true
+}
+
+// Delete removes the value from its parent once the [WalkFunc] returns. Following items of a list are shifted.
+func (n *WalkNode) Delete() {
+	n.deleted = true
+}
+
+// WalkFunc is called by [Data.Walk] for every value.
+// Returning [SkipSubtree] skips the children of a map or list, any other error stops the walk.
+type WalkFunc func(node *WalkNode) error
+
+// Walk visits every value of the Data depth-first, maps and lists are visited before their children.
+// The keys of maps are visited in sorted order, hence the order is deterministic.
+// Values can be replaced and deleted during the walk, see [WalkNode].
+func (d Data) Walk(fn WalkFunc) error {
+	_, _, err := walkChildren(d, nil, fn)
+	return err
+}
+
+// walkChildren walks all children of the map or list.
+// The node is only written to if a child has been replaced or deleted, hence read-only walks never modify the Data.
+// It returns the node and true if it is a new list, because items of the list have been deleted.
+func walkChildren(node interface{}, path []interface{}, fn WalkFunc) (interface{}, bool, error) {
+	switch n := node.(type) {
+	case Data, map[string]interface{}:
+		nodeMap, _ := asData(n)
+		for _, key := range nodeMap.sortedKeys() {
+			child, keep, changed, err := walkChild(&WalkNode{Path: appendPath(path, key), Value: nodeMap[key], Parent: node}, fn)
+			if err != nil {
+				return nil, false, err
+			}
+			switch {
+			case !keep:
+				delete(nodeMap, key)
+			case changed:
+				nodeMap[key] = child
+			}
+		}
+		return node, false, nil
+
+	case []interface{}:
+		// out is only created once an item is deleted, until then the list is modified in place
+		var out []interface{}
+		for i, item := range n {
+			child, keep, changed, err := walkChild(&WalkNode{Path: appendPath(path, i), Value: item, Parent: node}, fn)
+			if err != nil {
+				return nil, false, err
+			}
+			if !keep && out == nil {
+				out = append(make([]interface{}, 0, len(n)-1), n[:i]...)
+			}
+			switch {
+			case !keep:
+			case out != nil:
+				out = append(out, child)
+			case changed:
+				n[i] = child
+			}
+		}
+		if out != nil {
+			return out, true, nil
+		}
+		return n, false, nil
+	}
+
+	return node, false, nil
+}
+
+// walkChild calls fn for the node and walks its children.
+// It returns the new value of the node, false if the node has been deleted
+// and true if the new value has to be written to the parent.
+func walkChild(node *WalkNode, fn WalkFunc) (interface{}, bool, bool, error) {
+	err := fn(node)
+	skip := errors.Is(err, SkipSubtree)
+	if err != nil && !skip {
+		return nil, false, false, err
+	}
+
+	switch {
+	case node.deleted:
+		return nil, false, false, nil
+	case node.replaced:
+		return node.replacement, true, true, nil
+	case skip:
+		return node.Value, true, false, nil
+	}
+
+	value, changed, err := walkChildren(node.Value, node.Path, fn)
+	if err != nil {
+		return nil, false, false, err
+	}
+	return value, true, changed, nil
+}
@@ -0,0 +1,115 @@
+package skipper_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukasjarosch/skipper"
+)
+
+func TestDataWalk(t *testing.T) {
+	data := skipper.Data{
+		"b": map[string]interface{}{"enabled": true, "count": 3, "empty": nil},
+		"a": []interface{}{"x", 1.5},
+	}
+
+	var visited []string
+	err := data.Walk(func(node *skipper.WalkNode) error {
+		visited = append(visited, fmt.Sprintf("%s=%T", skipper.FormatPath(node.Path), node.Value))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"a=[]interface {}",
+		"a[0]=string",
+		"a[1]=float64",
+		"b=map[string]interface {}",
+		"b.count=int",
+		"b.empty=<nil>",
+		"b.enabled=bool",
+	}, visited)
+}
+
+func TestDataWalkModify(t *testing.T) {
+	data := skipper.Data{
+		"list":   []interface{}{"keep", "drop", "keep", "drop"},
+		"secret": map[string]interface{}{"password": "hunter2", "nested": map[string]interface{}{"token": "abc"}},
+		"skip":   map[string]interface{}{"drop": "value"},
+		"count":  1,
+	}
+
+	var visited []string
+	err := data.Walk(func(node *skipper.WalkNode) error {
+		visited = append(visited, skipper.FormatPath(node.Path))
+		switch {
+		case node.Value == "drop":
+			node.Delete()
+		case node.Key() == "secret":
+			node.Replace("<redacted>")
+		case node.Key() == "skip":
+			return skipper.SkipSubtree
+		case node.Key() == "count":
+			assert.Equal(t, data, node.Parent)
+			node.Replace(node.Value.(int) + 1)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, skipper.Data{
+		"list":   []interface{}{"keep", "keep"},
+		"secret": "<redacted>",
+		"skip":   map[string]interface{}{"drop": "value"},
+		"count":  2,
+	}, data)
+	// replaced values and skipped subtrees are not walked
+	assert.Equal(t, []string{"count", "list", "list[0]", "list[1]", "list[2]", "list[3]", "secret", "skip"}, visited)
+
+	err = data.Walk(func(node *skipper.WalkNode) error {
+		return fmt.Errorf("stop at %s", skipper.FormatPath(node.Path))
+	})
+	assert.EqualError(t, err, "stop at count")
+}
+
+func TestDataFindValuesTyped(t *testing.T) {
+	data := skipper.Data{
+		"int":   5,
+		"bool":  true,
+		"nil":   nil,
+		"list":  []interface{}{"${a}", 1},
+		"float": 1.5,
+	}
+
+	var found []interface{}
+	err := data.FindValues(func(value string, path []interface{}) (interface{}, error) {
+		return fmt.Sprintf("%s=%s", skipper.FormatPath(path), value), nil
+	}, &found)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"bool=true", "float=1.5", "int=5", "list[0]=${a}", "list[1]=1"}, found)
+}
+
+func TestDataFindValuesConcurrent(t *testing.T) {
+	data := skipper.Data{
+		"map":  map[string]interface{}{"a": "${x}", "b": 1},
+		"list": []interface{}{"${y}", map[string]interface{}{"c": true}},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var found []interface{}
+			err := data.FindValues(func(value string, path []interface{}) (interface{}, error) {
+				return value, nil
+			}, &found)
+			assert.NoError(t, err)
+			assert.Len(t, found, 4)
+		}()
+	}
+	wg.Wait()
+}